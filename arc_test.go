@@ -112,3 +112,81 @@ func TestARCHas(t *testing.T) {
 		})
 	}
 }
+
+func TestARCResize(t *testing.T) {
+	gc := buildTestCache[int, int](t, TYPE_ARC, 10)
+	p := gc.(*ARC[int, int]).policy.(*arcPolicy[int])
+
+	for i := 0; i < 30; i++ {
+		gc.Set(i, i)
+		gc.Get(i - i%2)
+	}
+	gc.Resize(4)
+	if l := gc.Len(false); l != 4 {
+		t.Fatalf("%v != 4", l)
+	}
+	if p.part > 4 {
+		t.Errorf("part %v exceeds the new size", p.part)
+	}
+	if l := p.t1.Len() + p.b1.Len(); l > 4 {
+		t.Errorf("t1+b1 %v exceeds the new size", l)
+	}
+	if l := p.t1.Len() + p.t2.Len() + p.b1.Len() + p.b2.Len(); l > 8 {
+		t.Errorf("directory size %v exceeds twice the new size", l)
+	}
+
+	gc.Resize(40)
+	for i := 100; i < 140; i++ {
+		gc.Set(i, i)
+	}
+	if l := gc.Len(false); l != 40 {
+		t.Fatalf("%v != 40", l)
+	}
+	if l := p.t1.Len() + p.t2.Len(); l != 40 {
+		t.Fatalf("%v != 40", l)
+	}
+}
+
+func TestARCWeightedTargets(t *testing.T) {
+	// the size passed to New does not limit a weighted cache
+	gc := New[int, int](10).ARC().MaximumWeight(1000).Build()
+	p := gc.(*ARC[int, int]).policy.(*arcPolicy[int])
+
+	for i := 0; i < 1000; i++ {
+		gc.Set(i, i)
+	}
+	for i := 0; i < 500; i++ {
+		gc.Get(i)
+	}
+	for i := 1000; i < 3000; i++ {
+		gc.Set(i, i)
+	}
+
+	if l := gc.Len(false); l != 1000 {
+		t.Fatalf("%v != 1000", l)
+	}
+	if p.size != 1000 {
+		t.Fatalf("%v != 1000", p.size)
+	}
+	if l := p.t1.Len() + p.b1.Len(); l > 1000 {
+		t.Errorf("t1+b1 %v exceeds the size", l)
+	}
+	for i := 0; i < 500; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("frequently used key %v should have been kept in t2", i)
+		}
+	}
+}
+
+func TestARCUpdateStaysInT1(t *testing.T) {
+	gc := buildTestCache[int, int](t, TYPE_ARC, 2)
+	gc.Set(1, 1)
+	gc.Set(2, 2)
+	gc.Get(1)
+	// an update leaves 2 in t1, so it is evicted before 1 in t2
+	gc.Set(2, 3)
+	gc.Set(3, 3)
+	if !gc.Has(1) || gc.Has(2) {
+		t.Fatal("the key which has only been updated should have been evicted")
+	}
+}
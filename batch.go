@@ -0,0 +1,89 @@
+package gcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batcher merges the keys of single-key loads arriving within a time window
+// into one call of a bulk load function. A batch is loaded once its window is
+// over or it holds maxSize keys.
+type batcher[K comparable, V any] struct {
+	window  time.Duration
+	maxSize int
+	load    func(context.Context, []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	ctx    context.Context
+	keys   []K
+	done   chan struct{}
+	values map[K]V
+	err    error
+	timer  *time.Timer
+}
+
+func newBatcher[K comparable, V any](window time.Duration, maxSize int, load func(context.Context, []K) (map[K]V, error)) *batcher[K, V] {
+	return &batcher[K, V]{window: window, maxSize: maxSize, load: load}
+}
+
+// Load adds key to the pending batch and waits for the batch to be loaded or
+// for ctx to be done. The batch is loaded with the values of the context of
+// its first key, but without its cancellation.
+func (b *batcher[K, V]) Load(ctx context.Context, key K) (v V, _ error) {
+	b.mu.Lock()
+	bt := b.pending
+	if bt == nil {
+		bt = &batch[K, V]{ctx: context.WithoutCancel(ctx), done: make(chan struct{})}
+		b.pending = bt
+		bt.timer = time.AfterFunc(b.window, func() {
+			b.dispatch(bt)
+		})
+	}
+	bt.keys = append(bt.keys, key)
+	full := b.maxSize > 0 && len(bt.keys) >= b.maxSize
+	if full {
+		b.pending = nil
+		bt.timer.Stop()
+	}
+	b.mu.Unlock()
+	if full {
+		go b.run(bt)
+	}
+
+	select {
+	case <-bt.done:
+	case <-ctx.Done():
+		return v, ctx.Err()
+	}
+	if bt.err != nil {
+		return v, bt.err
+	}
+	v, ok := bt.values[key]
+	if !ok {
+		return v, KeyNotFoundError
+	}
+	return v, nil
+}
+
+// dispatch loads bt once its window is over, unless it has already been
+// loaded because it was full.
+func (b *batcher[K, V]) dispatch(bt *batch[K, V]) {
+	b.mu.Lock()
+	if b.pending != bt {
+		b.mu.Unlock()
+		return
+	}
+	b.pending = nil
+	b.mu.Unlock()
+	b.run(bt)
+}
+
+func (b *batcher[K, V]) run(bt *batch[K, V]) {
+	bt.values, bt.err = b.load(bt.ctx, bt.keys)
+	close(bt.done)
+}
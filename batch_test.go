@@ -0,0 +1,128 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type bulkRecorder struct {
+	mu    sync.Mutex
+	calls [][]int
+}
+
+func (r *bulkRecorder) load(_ context.Context, keys []int) (map[int]int, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, keys)
+	r.mu.Unlock()
+	values := make(map[int]int, len(keys))
+	for _, key := range keys {
+		values[key] = key * 10
+	}
+	return values, nil
+}
+
+func (r *bulkRecorder) Calls() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func getConcurrently(t *testing.T, gc Cache[int, int], keys []int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := gc.Get(key); err != nil || v != key*10 {
+				t.Errorf("unexpected %v, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBatchWindow(t *testing.T) {
+	r := &bulkRecorder{}
+	gc := New[int, int](100).
+		LRU().
+		BulkLoaderFunc(r.load).
+		BatchWindow(20*time.Millisecond, 0).
+		Build()
+
+	keys := make([]int, 20)
+	for i := range keys {
+		keys[i] = i % 10
+	}
+	getConcurrently(t, gc, keys)
+	calls := r.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+	// duplicate keys are loaded once
+	if len(calls[0]) != 10 {
+		t.Fatalf("unexpected keys %v", calls[0])
+	}
+	if l := gc.Len(false); l != 10 {
+		t.Fatalf("%v != 10", l)
+	}
+}
+
+func TestBatchWindowMaxSize(t *testing.T) {
+	r := &bulkRecorder{}
+	gc := New[int, int](100).
+		LRU().
+		BulkLoaderFunc(r.load).
+		BatchWindow(time.Minute, 5).
+		Build()
+
+	keys := make([]int, 20)
+	for i := range keys {
+		keys[i] = i
+	}
+	getConcurrently(t, gc, keys)
+	calls := r.Calls()
+	if len(calls) != 4 {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+	for _, keys := range calls {
+		if len(keys) != 5 {
+			t.Fatalf("unexpected calls %v", calls)
+		}
+	}
+}
+
+func TestBatchWindowContext(t *testing.T) {
+	r := &bulkRecorder{}
+	gc := New[int, int](100).
+		LRU().
+		BulkLoaderFunc(r.load).
+		BatchWindow(50*time.Millisecond, 0).
+		Build()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := gc.GetWithContext(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if d := time.Since(start); d >= 50*time.Millisecond {
+		t.Fatalf("waited for %v", d)
+	}
+	// the batch is loaded anyway
+	waitFor(t, func() bool {
+		return gc.Has(1)
+	})
+}
+
+func TestBatchWindowWithoutBulkLoader(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Build should panic")
+		}
+	}()
+	New[int, int](10).BatchWindow(time.Millisecond, 0).Build()
+}
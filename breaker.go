@@ -0,0 +1,169 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLoaderCircuitOpen is returned instead of calling the loader while the
+// circuit breaker of the cache is open.
+var ErrLoaderCircuitOpen = errors.New("gcache: loader circuit open")
+
+// breakerBuckets is the number of buckets of the rolling window.
+const breakerBuckets = 10
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker stops calls of the loader once their error rate over a
+// rolling window reaches errorRate. After openTimeout a single probe call is
+// let through; its success closes the circuit and its failure opens it again.
+// Every change of the state starts a new generation, and only the results of
+// calls allowed in the current generation are recorded.
+type circuitBreaker struct {
+	errorRate   float64
+	minRequests int
+	window      time.Duration
+	openTimeout time.Duration
+	clock       Clock
+
+	mu         sync.Mutex
+	state      circuitState
+	generation uint64
+	openedAt   time.Time
+	probing    bool
+	buckets    [breakerBuckets]breakerBucket
+}
+
+// breakerBucket counts the calls of a slice of the rolling window.
+type breakerBucket struct {
+	slice     int64
+	successes int
+	failures  int
+}
+
+func newCircuitBreaker(errorRate float64, minRequests int, window, openTimeout time.Duration, clock Clock) *circuitBreaker {
+	return &circuitBreaker{
+		errorRate:   errorRate,
+		minRequests: minRequests,
+		window:      window,
+		openTimeout: openTimeout,
+		clock:       clock,
+	}
+}
+
+// instance returns a breaker with the configuration of b using clock. A
+// breaker built by the CacheBuilder only holds the configuration and gets a
+// new instance for every cache, while an instance is returned as it is.
+func (b *circuitBreaker) instance(clock Clock) *circuitBreaker {
+	if b == nil || b.clock != nil {
+		return b
+	}
+	return newCircuitBreaker(b.errorRate, b.minRequests, b.window, b.openTimeout, clock)
+}
+
+// allow returns ErrLoaderCircuitOpen if a call must not be made. Every allowed
+// call must be followed by record with the returned generation. A nil breaker
+// allows all calls.
+func (b *circuitBreaker) allow() (uint64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.openTimeout {
+			return 0, ErrLoaderCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+	case circuitHalfOpen:
+		if b.probing {
+			return 0, ErrLoaderCircuitOpen
+		}
+		b.probing = true
+	}
+	return b.generation, nil
+}
+
+// probe reports whether the call allowed in generation is the probe of a
+// half-open circuit.
+func (b *circuitBreaker) probe(generation uint64) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == circuitHalfOpen && b.generation == generation
+}
+
+// record records the result of a call allowed in generation. Calls allowed
+// before the last change of the state are ignored, so that only the probe
+// decides about a half-open circuit. Cancelled calls and KeyNotFoundError
+// count neither as success nor as failure.
+func (b *circuitBreaker) record(generation uint64, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	neutral := errors.Is(err, context.Canceled) || errors.Is(err, KeyNotFoundError)
+	now := b.clock.Now()
+	if b.state == circuitHalfOpen {
+		b.probing = false
+		switch {
+		case neutral:
+		case err != nil:
+			b.setState(circuitOpen)
+			b.openedAt = now
+		default:
+			b.setState(circuitClosed)
+			b.buckets = [breakerBuckets]breakerBucket{}
+		}
+		return
+	}
+	if neutral || b.state != circuitClosed {
+		return
+	}
+
+	slice := now.UnixNano() / max(int64(b.window/breakerBuckets), 1)
+	bucket := &b.buckets[slice%breakerBuckets]
+	if bucket.slice != slice {
+		*bucket = breakerBucket{slice: slice}
+	}
+	if err == nil {
+		bucket.successes++
+		return
+	}
+	bucket.failures++
+
+	var successes, failures int
+	for _, bucket := range b.buckets {
+		if slice-bucket.slice < breakerBuckets {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+	total := successes + failures
+	if total >= b.minRequests && float64(failures) >= b.errorRate*float64(total) {
+		b.setState(circuitOpen)
+		b.openedAt = now
+	}
+}
+
+// setState changes the state and starts a new generation.
+func (b *circuitBreaker) setState(state circuitState) {
+	b.state = state
+	b.generation++
+}
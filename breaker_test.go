@@ -0,0 +1,124 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	clock := NewFakeClock()
+	b := newCircuitBreaker(0.5, 4, 10*time.Second, time.Minute, clock)
+	errFailed := errors.New("failed")
+
+	for _, err := range []error{nil, errFailed, KeyNotFoundError, context.Canceled, errFailed} {
+		gen, e := b.allow()
+		if e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+		b.record(gen, err)
+	}
+	if b.state != circuitClosed {
+		t.Fatal("circuit opened below minRequests")
+	}
+	gen, _ := b.allow()
+	b.record(gen, errFailed)
+	if b.state != circuitOpen {
+		t.Fatal("circuit not opened")
+	}
+	if _, err := b.allow(); err != ErrLoaderCircuitOpen {
+		t.Fatalf("%v != %v", err, ErrLoaderCircuitOpen)
+	}
+
+	clock.Advance(time.Minute)
+	gen, err := b.allow()
+	if err != nil {
+		t.Fatalf("probe not allowed: %v", err)
+	}
+	if _, err := b.allow(); err != ErrLoaderCircuitOpen {
+		t.Fatal("second probe allowed")
+	}
+	b.record(gen, errFailed)
+	if b.state != circuitOpen {
+		t.Fatal("failed probe did not open the circuit")
+	}
+
+	clock.Advance(time.Minute)
+	gen, _ = b.allow()
+	b.record(gen, context.Canceled)
+	if b.state != circuitHalfOpen {
+		t.Fatal("cancelled probe changed the state")
+	}
+	gen, _ = b.allow()
+	b.record(gen, nil)
+	if b.state != circuitClosed {
+		t.Fatal("successful probe did not close the circuit")
+	}
+	gen, _ = b.allow()
+	b.record(gen, errFailed)
+	if b.state != circuitClosed {
+		t.Fatal("errors before closing counted")
+	}
+}
+
+func TestCircuitBreakerStaleCall(t *testing.T) {
+	clock := NewFakeClock()
+	b := newCircuitBreaker(0.5, 2, 10*time.Second, time.Minute, clock)
+	errFailed := errors.New("failed")
+
+	// a slow call is allowed while the circuit is closed
+	slow, _ := b.allow()
+	for i := 0; i < 2; i++ {
+		gen, _ := b.allow()
+		b.record(gen, errFailed)
+	}
+	if b.state != circuitOpen {
+		t.Fatal("circuit not opened")
+	}
+
+	clock.Advance(time.Minute)
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatalf("probe not allowed: %v", err)
+	}
+	b.record(slow, nil)
+	if b.state != circuitHalfOpen || !b.probing {
+		t.Fatal("a call allowed before the circuit opened decided the probe")
+	}
+	if _, err := b.allow(); err != ErrLoaderCircuitOpen {
+		t.Fatal("second probe allowed")
+	}
+	b.record(probe, errFailed)
+	if b.state != circuitOpen {
+		t.Fatal("failed probe did not open the circuit")
+	}
+}
+
+func TestCircuitBreakerRollingWindow(t *testing.T) {
+	clock := NewFakeClock()
+	b := newCircuitBreaker(0.5, 4, 10*time.Second, time.Minute, clock)
+	errFailed := errors.New("failed")
+
+	for i := 0; i < 3; i++ {
+		gen, _ := b.allow()
+		b.record(gen, errFailed)
+	}
+	clock.Advance(11 * time.Second)
+	gen, _ := b.allow()
+	b.record(gen, errFailed)
+	if b.state != circuitClosed {
+		t.Fatal("errors outside the window counted")
+	}
+	for i := 0; i < 2; i++ {
+		clock.Advance(3 * time.Second)
+		gen, _ := b.allow()
+		b.record(gen, nil)
+	}
+	clock.Advance(3 * time.Second)
+	gen, _ = b.allow()
+	b.record(gen, errFailed)
+	if b.state != circuitOpen {
+		t.Fatal("circuit not opened")
+	}
+}
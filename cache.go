@@ -9,14 +9,43 @@ import (
 )
 
 const (
-	TYPE_SIMPLE = "simple"
-	TYPE_LRU    = "lru"
-	TYPE_LFU    = "lfu"
-	TYPE_ARC    = "arc"
+	TYPE_SIMPLE  = "simple"
+	TYPE_LRU     = "lru"
+	TYPE_LFU     = "lfu"
+	TYPE_ARC     = "arc"
+	TYPE_TINYLFU = "tinylfu"
+	TYPE_S3FIFO  = "s3fifo"
+	TYPE_SIEVE   = "sieve"
 )
 
 var KeyNotFoundError = errors.New("key not found")
 
+// EntryTooHeavyError is returned when the weight of a single entry exceeds
+// the maximum weight of the whole cache.
+type EntryTooHeavyError struct {
+	Weight        int64
+	MaximumWeight int64
+}
+
+func (e *EntryTooHeavyError) Error() string {
+	return fmt.Sprintf("gcache: entry weight %d exceeds maximum weight %d", e.Weight, e.MaximumWeight)
+}
+
+// StaleValueError is returned along with an expired value by Get when the
+// loader failed and the cache serves the value under StaleIfError. Err is
+// the error of the loader.
+type StaleValueError struct {
+	Err error
+}
+
+func (e *StaleValueError) Error() string {
+	return "gcache: serving stale value: " + e.Err.Error()
+}
+
+func (e *StaleValueError) Unwrap() error {
+	return e.Err
+}
+
 type Cache[K comparable, V any] interface {
 	// Set inserts or updates the specified key-value pair.
 	Set(key K, value V) error
@@ -34,12 +63,21 @@ type Cache[K comparable, V any] interface {
 	GetIFPresent(K) (V, error)
 	GetWithContext(context.Context, K) (V, error)
 	GetIFPresentWithContext(context.Context, K) (V, error)
+	// GetMany returns the values of the specified keys. Keys present in the
+	// cache are served from the cache, and the others are loaded with a
+	// single call of the BulkLoaderFunc, or one by one with the LoaderFunc.
+	// Keys which are not found, or whose loader error is cached by
+	// NegativeTTL, are missing from the returned map.
+	GetMany(ctx context.Context, keys []K) (map[K]V, error)
 	// GetALL returns a map containing all key-value pairs in the cache.
 	GetALL(checkExpired bool) map[K]V
 	get(key K, onLoad bool) (V, error)
 	// Remove removes the specified key from the cache if the key is present.
 	// Returns true if the key was present and the key has been deleted.
 	Remove(key K) bool
+	// RemoveByTag removes the keys tagged with tag by a ResultLoaderFunc.
+	// Returns the number of keys deleted.
+	RemoveByTag(tag string) int
 	// Purge removes all key-value pairs from the cache.
 	Purge()
 	// Keys returns a slice containing all keys in the cache.
@@ -48,46 +86,129 @@ type Cache[K comparable, V any] interface {
 	Len(checkExpired bool) int
 	// Has returns true if the key exists in the cache.
 	Has(key K) bool
+	// Resize changes the number of items the cache can hold, or its maximum
+	// weight if it has a MaximumWeight, evicting items if the cache shrinks.
+	Resize(newSize int)
+	// Close stops the background cleanup of expired items. The cache remains
+	// usable afterwards.
+	Close()
 
 	statsAccessor
 }
 
 type baseCache[K comparable, V any] struct {
-	clock            Clock
-	size             int
-	loaderExpireFunc LoaderExpireFunc[K, V]
-	evictedFunc      EvictedFunc[K, V]
-	purgeVisitorFunc PurgeVisitorFunc[K, V]
-	addedFunc        AddedFunc[K, V]
-	deserializeFunc  DeserializeFunc[K, V]
-	serializeFunc    SerializeFunc[K, V]
-	expiration       *time.Duration
-	mu               sync.RWMutex
-	loadGroup        Group[K, V]
+	clock             Clock
+	size              int
+	loadFunc          func(context.Context, K) (entryValue[V], error)
+	bulkLoaderFunc    BulkLoaderFunc[K, V]
+	loaderTimeout     time.Duration
+	retry             *retryPolicy
+	breaker           *circuitBreaker
+	limiter           *loadLimiter
+	hedger            *hedger
+	evictedFunc       EvictedFunc[K, V]
+	purgeVisitorFunc  PurgeVisitorFunc[K, V]
+	addedFunc         AddedFunc[K, V]
+	deserializeFunc   DeserializeFunc[K, V]
+	serializeFunc     SerializeFunc[K, V]
+	expiration        *time.Duration
+	accessExpiration  *time.Duration
+	expiry            Expiry[K, V]
+	refreshAfterWrite *time.Duration
+	staleIfError      time.Duration
+	negativeTTL       time.Duration
+	negativeCacheable func(error) bool
+	earlyRefresh      float64
+	weigher           Weigher[K, V]
+	maximumWeight     int64
+	mu                sync.RWMutex
+	loadGroup         Group[K, V]
 	*stats
+
+	// maximumEntryWeight is the maximum weight of a single entry, which is
+	// larger than maximumWeight for the shards of a sharded cache.
+	maximumEntryWeight int64
 }
 
 type (
 	LoaderFunc[K comparable, V any]       func(context.Context, K) (V, error)
 	LoaderExpireFunc[K comparable, V any] func(context.Context, K) (V, *time.Duration, error)
+	BulkLoaderFunc[K comparable, V any]   func(context.Context, []K) (map[K]V, error)
 	EvictedFunc[K comparable, V any]      func(K, V)
 	PurgeVisitorFunc[K comparable, V any] func(K, V)
 	AddedFunc[K comparable, V any]        func(K, V)
 	DeserializeFunc[K comparable, V any]  func(K, V) (V, error)
 	SerializeFunc[K comparable, V any]    func(K, V) (V, error)
+	Weigher[K comparable, V any]          func(K, V) int64
+	ResultLoaderFunc[K comparable, V any] func(context.Context, K) (LoadResult[V], error)
 )
 
+// LoadResult is a value returned by a ResultLoaderFunc together with the
+// metadata of its cache entry.
+type LoadResult[V any] struct {
+	Value V
+	// TTL is the expiration of the value. Zero means the expiration of the
+	// cache.
+	TTL time.Duration
+	// Weight is the weight of the entry for MaximumWeight. Zero means the
+	// weight computed by the Weigher.
+	Weight int64
+	// NoCache returns the value to the callers of the load without storing
+	// it, for example for partial or degraded responses.
+	NoCache bool
+	// Tags are the tags of the entry, for RemoveByTag.
+	Tags []string
+	// Version is the version of the value. A value with a lower version than
+	// the cached value of its key is returned without replacing it.
+	Version uint64
+}
+
+// entry returns the entryValue of r.
+func (r LoadResult[V]) entry() entryValue[V] {
+	ev := entryValue[V]{value: r.Value, weight: r.Weight, noCache: r.NoCache, tags: r.Tags, version: r.Version}
+	if r.TTL != 0 {
+		ttl := r.TTL
+		ev.expiration = &ttl
+	}
+	return ev
+}
+
 type CacheBuilder[K comparable, V any] struct {
-	clock            Clock
-	tp               string
-	size             int
-	loaderExpireFunc LoaderExpireFunc[K, V]
-	evictedFunc      EvictedFunc[K, V]
-	purgeVisitorFunc PurgeVisitorFunc[K, V]
-	addedFunc        AddedFunc[K, V]
-	expiration       *time.Duration
-	deserializeFunc  DeserializeFunc[K, V]
-	serializeFunc    SerializeFunc[K, V]
+	clock             Clock
+	tp                string
+	size              int
+	loadFunc          func(context.Context, K) (entryValue[V], error)
+	loaderChain       []LoaderStage[K, V]
+	bulkLoaderFunc    BulkLoaderFunc[K, V]
+	loaderTimeout     time.Duration
+	retry             *retryPolicy
+	breaker           *circuitBreaker
+	limiter           *loadLimiter
+	hedger            *hedger
+	evictedFunc       EvictedFunc[K, V]
+	purgeVisitorFunc  PurgeVisitorFunc[K, V]
+	addedFunc         AddedFunc[K, V]
+	expiration        *time.Duration
+	accessExpiration  *time.Duration
+	expiry            Expiry[K, V]
+	refreshAfterWrite *time.Duration
+	staleIfError      time.Duration
+	negativeTTL       time.Duration
+	negativeCacheable func(error) bool
+	earlyRefresh      float64
+	deserializeFunc   DeserializeFunc[K, V]
+	serializeFunc     SerializeFunc[K, V]
+	weigher           Weigher[K, V]
+	maximumWeight     int64
+	shards            int
+	cleanupInterval   time.Duration
+	batchWindow       time.Duration
+	maxBatchSize      int
+	cancelAbandoned   bool
+	detachLoader      bool
+
+	// maximumEntryWeight is set for the shards of a sharded cache.
+	maximumEntryWeight int64
 }
 
 func New[K comparable, V any](size int) *CacheBuilder[K, V] {
@@ -106,10 +227,11 @@ func (cb *CacheBuilder[K, V]) Clock(clock Clock) *CacheBuilder[K, V] {
 // LoaderFunc Set a loader function. loaderFunc: create a new value with this
 // function if cached value is expired.
 func (cb *CacheBuilder[K, V]) LoaderFunc(loaderFunc LoaderFunc[K, V]) *CacheBuilder[K, V] {
-	cb.loaderExpireFunc = func(ctx context.Context, k K) (V, *time.Duration, error) {
+	cb.loadFunc = func(ctx context.Context, k K) (entryValue[V], error) {
 		v, err := loaderFunc(ctx, k)
-		return v, nil, err
+		return entryValue[V]{value: v}, err
 	}
+	cb.loaderChain = nil
 	return cb
 }
 
@@ -118,7 +240,126 @@ func (cb *CacheBuilder[K, V]) LoaderFunc(loaderFunc LoaderFunc[K, V]) *CacheBuil
 // returned instead of time.Duration from loaderExpireFunc than value will never
 // expire.
 func (cb *CacheBuilder[K, V]) LoaderExpireFunc(loaderExpireFunc LoaderExpireFunc[K, V]) *CacheBuilder[K, V] {
-	cb.loaderExpireFunc = loaderExpireFunc
+	cb.loadFunc = expireLoader(loaderExpireFunc)
+	cb.loaderChain = nil
+	return cb
+}
+
+// ResultLoaderFunc Set a loader function returning a LoadResult, which carries
+// the TTL, weight, tags and version of the entry besides the value, and can
+// return a value without storing it.
+func (cb *CacheBuilder[K, V]) ResultLoaderFunc(resultLoaderFunc ResultLoaderFunc[K, V]) *CacheBuilder[K, V] {
+	cb.loadFunc = func(ctx context.Context, k K) (entryValue[V], error) {
+		r, err := resultLoaderFunc(ctx, k)
+		return r.entry(), err
+	}
+	cb.loaderChain = nil
+	return cb
+}
+
+// LoaderChain Set several loaders called in priority order. A stage failing
+// or exceeding its timeout moves the load on to the next stage, while
+// KeyNotFoundError ends it. Values are stored with the TTL of the stage which
+// loaded them, so that values of a degraded fallback can expire sooner.
+// StageHitCount returns the number of values loaded by each stage. It
+// replaces the loader function.
+func (cb *CacheBuilder[K, V]) LoaderChain(stages ...LoaderStage[K, V]) *CacheBuilder[K, V] {
+	cb.loaderChain = stages
+	cb.loadFunc = nil
+	return cb
+}
+
+// BulkLoaderFunc Set a function loading several values at once, used by
+// GetMany for all keys missing from the cache. Keys missing from the returned
+// map are not found.
+func (cb *CacheBuilder[K, V]) BulkLoaderFunc(bulkLoaderFunc BulkLoaderFunc[K, V]) *CacheBuilder[K, V] {
+	cb.bulkLoaderFunc = bulkLoaderFunc
+	return cb
+}
+
+// BatchWindow Merge the keys of concurrent Get and GetIFPresent misses into
+// one call of the BulkLoaderFunc. A batch collects keys for the window, or
+// until it holds maxBatchSize keys if maxBatchSize > 0. Each caller stops
+// waiting once its context is done, and duplicate keys are loaded only once.
+// Misses are then only loaded by the BulkLoaderFunc.
+func (cb *CacheBuilder[K, V]) BatchWindow(window time.Duration, maxBatchSize int) *CacheBuilder[K, V] {
+	cb.batchWindow = window
+	cb.maxBatchSize = maxBatchSize
+	return cb
+}
+
+// CancelAbandonedLoads Cancel the context of a load once every caller waiting
+// for it has given up because its own context is done. The loader then gets a
+// context with the values of the first caller's context, but which is only
+// cancelled that way.
+func (cb *CacheBuilder[K, V]) CancelAbandonedLoads() *CacheBuilder[K, V] {
+	cb.cancelAbandoned = true
+	return cb
+}
+
+// LoaderTimeout Set the maximum duration of a call of the loader or the bulk
+// loader. The context passed to the loader is cancelled after timeout.
+func (cb *CacheBuilder[K, V]) LoaderTimeout(timeout time.Duration) *CacheBuilder[K, V] {
+	cb.loaderTimeout = timeout
+	return cb
+}
+
+// DetachLoaderContext Run loads under a context which keeps the values of the
+// first caller's context, such as trace IDs, but is not cancelled with it.
+// A caller giving up then does not fail the load shared with other callers.
+// Combine it with LoaderTimeout to bound the duration of loads.
+func (cb *CacheBuilder[K, V]) DetachLoaderContext() *CacheBuilder[K, V] {
+	cb.detachLoader = true
+	return cb
+}
+
+// LoaderRetry Set a retry policy for failed calls of the loader or the bulk
+// loader. A call is attempted up to maxAttempts times. The n-th retry waits
+// between a half and the whole of backoff * 2^(n-1). Only errors for which
+// retryable returns true are retried; a nil retryable retries all errors but
+// KeyNotFoundError. Context errors are never retried, and retries stop once
+// the loader's context is done or its deadline would pass while waiting.
+// Retries happen inside the shared load, so all callers waiting for a key
+// get the final result, and they are counted in RetryCount.
+func (cb *CacheBuilder[K, V]) LoaderRetry(maxAttempts int, backoff time.Duration, retryable func(error) bool) *CacheBuilder[K, V] {
+	cb.retry = &retryPolicy{maxAttempts: maxAttempts, backoff: backoff, retryable: retryable}
+	return cb
+}
+
+// CircuitBreaker Set a circuit breaker around the loader and the bulk loader.
+// The circuit opens once at least minRequests loads completed within the
+// rolling window and the rate of their errors reaches errorRate. While it is
+// open, loads fail fast with ErrLoaderCircuitOpen instead of calling the
+// loader; with StaleIfError, values within the grace period are returned
+// instead. After openTimeout a single load probes the loader, and its success
+// closes the circuit again. KeyNotFoundError and cancelled loads are not
+// counted, and retries of LoaderRetry count as a single load.
+func (cb *CacheBuilder[K, V]) CircuitBreaker(errorRate float64, minRequests int, window, openTimeout time.Duration) *CacheBuilder[K, V] {
+	cb.breaker = newCircuitBreaker(errorRate, minRequests, window, openTimeout, nil)
+	return cb
+}
+
+// MaxConcurrentLoads Set the maximum number of concurrent calls of the loader
+// and the bulk loader across the whole cache. Loads of the same key are
+// shared and count once. If queue is true, excess loads wait for a running
+// load to complete or for their context to be done; otherwise they fail with
+// ErrTooManyLoads.
+func (cb *CacheBuilder[K, V]) MaxConcurrentLoads(n int, queue bool) *CacheBuilder[K, V] {
+	cb.limiter = &loadLimiter{n: n, queue: queue}
+	return cb
+}
+
+// HedgeLoads Set hedging of slow loader calls. If a call has not returned
+// after delay, a second call for the same key is started; the first
+// successful result wins and the context of the other call is cancelled.
+// With a percentile in (0, 1], such as 0.95, the delay is instead the
+// percentile of the recent latencies of the loader, once enough of them are
+// known. Hedging happens inside the shared load of a key, so waiters see a
+// single result. With MaxConcurrentLoads the second call needs a free slot of
+// its own and is skipped otherwise. The probe of a half-open CircuitBreaker
+// and the bulk loader are not hedged.
+func (cb *CacheBuilder[K, V]) HedgeLoads(delay time.Duration, percentile float64) *CacheBuilder[K, V] {
+	cb.hedger = newHedger(delay, percentile)
 	return cb
 }
 
@@ -143,6 +384,18 @@ func (cb *CacheBuilder[K, V]) ARC() *CacheBuilder[K, V] {
 	return cb.EvictType(TYPE_ARC)
 }
 
+func (cb *CacheBuilder[K, V]) TinyLFU() *CacheBuilder[K, V] {
+	return cb.EvictType(TYPE_TINYLFU)
+}
+
+func (cb *CacheBuilder[K, V]) S3FIFO() *CacheBuilder[K, V] {
+	return cb.EvictType(TYPE_S3FIFO)
+}
+
+func (cb *CacheBuilder[K, V]) Sieve() *CacheBuilder[K, V] {
+	return cb.EvictType(TYPE_SIEVE)
+}
+
 func (cb *CacheBuilder[K, V]) EvictedFunc(evictedFunc EvictedFunc[K, V]) *CacheBuilder[K, V] {
 	cb.evictedFunc = evictedFunc
 	return cb
@@ -173,11 +426,125 @@ func (cb *CacheBuilder[K, V]) Expiration(expiration time.Duration) *CacheBuilder
 	return cb
 }
 
+// ExpireAfterAccess Set a sliding expiration. An item expires once it has not
+// been read by Get or GetIFPresent, or written, for the given duration. If the
+// item also has an expiration time from Expiration, SetWithExpire or the
+// loader, it expires at whichever comes first.
+func (cb *CacheBuilder[K, V]) ExpireAfterAccess(expiration time.Duration) *CacheBuilder[K, V] {
+	cb.accessExpiration = &expiration
+	return cb
+}
+
+// Expiry Set a policy computing the expiration time of each item when it is
+// created, updated or read. It replaces Expiration and ExpireAfterAccess.
+func (cb *CacheBuilder[K, V]) Expiry(expiry Expiry[K, V]) *CacheBuilder[K, V] {
+	cb.expiry = expiry
+	return cb
+}
+
+// RefreshAfterWrite Set the age after which an item is reloaded. The first Get
+// or GetIFPresent of an item older than refresh, but not yet expired, returns
+// the current value and reloads it in the background with the loader. If the
+// reload fails, the current value is kept.
+func (cb *CacheBuilder[K, V]) RefreshAfterWrite(refresh time.Duration) *CacheBuilder[K, V] {
+	cb.refreshAfterWrite = &refresh
+	return cb
+}
+
+// StaleIfError Keep expired items for the grace period. If the loader fails
+// or panics while reloading such an item, Get returns the expired value with
+// a StaleValueError wrapping the loader error. After the grace period, the
+// item is removed and the error is returned. Len, Keys and GetALL skip the
+// kept items when checking for expired items.
+func (cb *CacheBuilder[K, V]) StaleIfError(grace time.Duration) *CacheBuilder[K, V] {
+	cb.staleIfError = grace
+	return cb
+}
+
+// NegativeTTL Cache the errors of the loader for which cacheable returns true
+// for ttl. Until then, Get and GetIFPresent return the cached error without
+// calling the loader. A nil cacheable only caches errors matching
+// KeyNotFoundError. Cached errors are not passed to the AddedFunc, and they
+// are removed by Set, Remove and Purge.
+func (cb *CacheBuilder[K, V]) NegativeTTL(ttl time.Duration, cacheable func(error) bool) *CacheBuilder[K, V] {
+	cb.negativeTTL = ttl
+	cb.negativeCacheable = cacheable
+	return cb
+}
+
+// EarlyRefresh Reload items in the background before they expire, with the
+// probabilistic early expiration of XFetch. Each Get or GetIFPresent of an
+// item triggers a reload with a probability growing as the expiration time
+// approaches and with the time the last load of the item took. A larger beta
+// favors earlier reloads, 1 is a good default. Replicas sharing a TTL thus
+// spread their reloads instead of all loading at the expiration time.
+func (cb *CacheBuilder[K, V]) EarlyRefresh(beta float64) *CacheBuilder[K, V] {
+	cb.earlyRefresh = beta
+	return cb
+}
+
+// Weigher Set a function computing the weight of an entry, for example its
+// size in bytes. The weight must not be negative. Without a weigher every
+// entry weighs 1.
+func (cb *CacheBuilder[K, V]) Weigher(weigher Weigher[K, V]) *CacheBuilder[K, V] {
+	cb.weigher = weigher
+	return cb
+}
+
+// MaximumWeight Set the maximum total weight of the cache. Once set, the
+// capacity is measured by weight instead of by the number of entries, and the
+// size passed to New only dimensions the eviction policy until the cache holds
+// more entries, or is full. The policy is then resized to the number of
+// entries. Setting an entry heavier than maximumWeight returns an
+// EntryTooHeavyError.
+func (cb *CacheBuilder[K, V]) MaximumWeight(maximumWeight int64) *CacheBuilder[K, V] {
+	cb.maximumWeight = maximumWeight
+	return cb
+}
+
+// Shards Split the cache into n independent shards, each with its own lock
+// and a share of the size and maximum weight. Keys are assigned to shards by
+// their hash. With n <= 1 the cache is not sharded, and n is limited to the
+// size, so that every shard holds at least one item.
+func (cb *CacheBuilder[K, V]) Shards(n int) *CacheBuilder[K, V] {
+	cb.shards = n
+	return cb
+}
+
+// CleanupInterval Set the interval of a background goroutine removing expired
+// items, which otherwise are only removed when they are accessed. Removed
+// items are passed to the EvictedFunc. Call Close to stop the goroutine.
+func (cb *CacheBuilder[K, V]) CleanupInterval(interval time.Duration) *CacheBuilder[K, V] {
+	cb.cleanupInterval = interval
+	return cb
+}
+
 func (cb *CacheBuilder[K, V]) Build() Cache[K, V] {
 	if cb.size <= 0 && cb.tp != TYPE_SIMPLE {
 		panic("gcache: Cache size <= 0")
 	}
+	if cb.batchWindow > 0 && cb.bulkLoaderFunc == nil {
+		panic("gcache: BatchWindow requires a BulkLoaderFunc")
+	}
+	if cb.expiry != nil && (cb.expiration != nil || cb.accessExpiration != nil) {
+		panic("gcache: Expiry cannot be combined with Expiration or ExpireAfterAccess")
+	}
+	if cb.retry != nil && cb.retry.maxAttempts < 1 {
+		panic("gcache: LoaderRetry maxAttempts < 1")
+	}
+	if cb.breaker != nil && (cb.breaker.errorRate <= 0 || cb.breaker.errorRate > 1 || cb.breaker.window <= 0) {
+		panic("gcache: CircuitBreaker errorRate not in (0, 1] or window <= 0")
+	}
+	if cb.limiter != nil && cb.limiter.n < 1 {
+		panic("gcache: MaxConcurrentLoads n < 1")
+	}
+	if cb.hedger != nil && (cb.hedger.delay <= 0 || cb.hedger.percentile < 0 || cb.hedger.percentile > 1) {
+		panic("gcache: HedgeLoads delay <= 0 or percentile not in [0, 1]")
+	}
 
+	if cb.shards > 1 {
+		return newShardedCache(cb)
+	}
 	return cb.build()
 }
 
@@ -191,37 +558,145 @@ func (cb *CacheBuilder[K, V]) build() Cache[K, V] {
 		return newLFUCache[K, V](cb)
 	case TYPE_ARC:
 		return newARC[K, V](cb)
+	case TYPE_TINYLFU:
+		return newTinyLFUCache[K, V](cb)
+	case TYPE_S3FIFO:
+		return newS3FIFOCache[K, V](cb)
+	case TYPE_SIEVE:
+		return newSieveCache[K, V](cb)
 	default:
-		panic("gcache: Unknown type " + cb.tp)
+		return newPolicyCache[K, V](cb, lookupEvictType[K](cb.tp)(cb.size))
 	}
 }
 
 func buildCache[K comparable, V any](c *baseCache[K, V], cb *CacheBuilder[K, V]) {
 	c.clock = cb.clock
 	c.size = cb.size
-	c.loaderExpireFunc = cb.loaderExpireFunc
+	c.loadFunc = cb.loadFunc
+	c.bulkLoaderFunc = cb.bulkLoaderFunc
+	c.loaderTimeout = cb.loaderTimeout
+	c.retry = cb.retry
+	c.breaker = cb.breaker.instance(cb.clock)
+	c.limiter = cb.limiter.instance()
+	c.hedger = cb.hedger.instance()
 	c.expiration = cb.expiration
+	c.accessExpiration = cb.accessExpiration
+	c.expiry = cb.expiry
+	c.refreshAfterWrite = cb.refreshAfterWrite
+	c.staleIfError = cb.staleIfError
+	c.negativeTTL = cb.negativeTTL
+	c.earlyRefresh = cb.earlyRefresh
+	c.negativeCacheable = cb.negativeCacheable
+	if c.negativeCacheable == nil {
+		c.negativeCacheable = func(err error) bool {
+			return errors.Is(err, KeyNotFoundError)
+		}
+	}
 	c.addedFunc = cb.addedFunc
 	c.deserializeFunc = cb.deserializeFunc
 	c.serializeFunc = cb.serializeFunc
 	c.evictedFunc = cb.evictedFunc
 	c.purgeVisitorFunc = cb.purgeVisitorFunc
-	c.stats = &stats{}
+	c.weigher = cb.weigher
+	c.maximumWeight = cb.maximumWeight
+	c.maximumEntryWeight = cb.maximumEntryWeight
+	if c.maximumEntryWeight <= 0 {
+		c.maximumEntryWeight = cb.maximumWeight
+	}
+	c.stats = &stats{stageHitCounts: make([]uint64, len(cb.loaderChain))}
+	if cb.loaderChain != nil {
+		c.loadFunc = expireLoader(chainLoader(cb.loaderChain, c.stats))
+	}
+}
+
+// loaderContext returns the context for a loader call, limited by the
+// LoaderTimeout.
+func (c *baseCache[K, V]) loaderContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.loaderTimeout > 0 {
+		return context.WithTimeout(ctx, c.loaderTimeout)
+	}
+	return ctx, func() {}
+}
+
+// entryValue is a value to store together with the metadata of its entry.
+type entryValue[V any] struct {
+	value      V
+	expiration *time.Duration
+	weight     int64
+	noCache    bool
+	tags       []string
+	version    uint64
+}
+
+// expireLoader turns a LoaderExpireFunc into a function returning an
+// entryValue.
+func expireLoader[K comparable, V any](loaderExpireFunc LoaderExpireFunc[K, V]) func(context.Context, K) (entryValue[V], error) {
+	if loaderExpireFunc == nil {
+		return nil
+	}
+	return func(ctx context.Context, k K) (entryValue[V], error) {
+		v, expiration, err := loaderExpireFunc(ctx, k)
+		return entryValue[V]{value: v, expiration: expiration}, err
+	}
 }
 
+// loadCallback receives the result of a loader call which took loadTime.
+type loadCallback[V any] func(ev entryValue[V], loadTime time.Duration, err error) (V, error)
+
 // load a new value using by specified key.
-func (c *baseCache[K, V]) load(ctx context.Context, key K, cb func(V, *time.Duration, error) (V, error), isWait bool) (V, bool, error) {
-	v, called, err := c.loadGroup.Do(key, func() (v V, e error) {
+func (c *baseCache[K, V]) load(ctx context.Context, key K, cb loadCallback[V], isWait bool) (V, bool, error) {
+	v, called, err := c.loadGroup.Do(ctx, key, c.loader(key, cb), isWait)
+	if err != nil {
+		var v V
+		return v, called, err
+	}
+	return v, called, nil
+}
+
+// reload a value in the background, unless a load for key is in flight.
+func (c *baseCache[K, V]) reload(ctx context.Context, key K, cb loadCallback[V]) {
+	c.loadGroup.refresh(ctx, key, c.loader(key, cb))
+}
+
+// loader returns a function calling the loader for key and passing its result
+// and the time it took to cb. A panicking loader is turned into an error.
+func (c *baseCache[K, V]) loader(key K, cb loadCallback[V]) func(context.Context) (V, error) {
+	return func(ctx context.Context) (v V, e error) {
+		if err := c.limiter.acquire(ctx); err != nil {
+			return v, err
+		}
+		defer c.limiter.release()
+		generation, err := c.breaker.allow()
+		if err != nil {
+			return v, err
+		}
+		recorded := false
 		defer func() {
 			if r := recover(); r != nil {
 				e = fmt.Errorf("loader panics: %v", r)
+				// a panic of cb comes after the result was recorded
+				if !recorded {
+					c.breaker.record(generation, e)
+				}
 			}
 		}()
-		return cb(c.loaderExpireFunc(ctx, key))
-	}, isWait)
-	if err != nil {
-		var v V
-		return v, called, err
+		ctx, cancel := c.loaderContext(ctx)
+		defer cancel()
+		start := c.clock.Now()
+		// the probe of a half-open circuit is a single call
+		hedger := c.hedger
+		if c.breaker.probe(generation) {
+			hedger = nil
+		}
+		var ev entryValue[V]
+		err = c.retry.do(ctx, func() (err error) {
+			ev, err = hedge(ctx, hedger, c.limiter, func(ctx context.Context) (entryValue[V], error) {
+				return c.loadFunc(ctx, key)
+			})
+			return err
+		}, func() { c.IncrRetryCount() })
+		c.breaker.record(generation, err)
+		recorded = true
+		return cb(ev, c.clock.Now().Sub(start), err)
 	}
-	return v, called, nil
 }
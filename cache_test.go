@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -18,6 +20,9 @@ func TestLoaderFunc(t *testing.T) {
 		New[int, int](size).LRU(),
 		New[int, int](size).LFU(),
 		New[int, int](size).ARC(),
+		New[int, int](size).TinyLFU(),
+		New[int, int](size).S3FIFO(),
+		New[int, int](size).Sieve(),
 	}
 	for _, builder := range testCaches {
 		var testCounter int64
@@ -57,6 +62,9 @@ func TestLoaderExpireFuncWithoutExpire(t *testing.T) {
 		New[int, int](size).LRU(),
 		New[int, int](size).LFU(),
 		New[int, int](size).ARC(),
+		New[int, int](size).TinyLFU(),
+		New[int, int](size).S3FIFO(),
+		New[int, int](size).Sieve(),
 	}
 	for _, builder := range testCaches {
 		var testCounter int64
@@ -96,6 +104,9 @@ func TestLoaderExpireFuncWithExpire(t *testing.T) {
 		New[int, int](size).LRU(),
 		New[int, int](size).LFU(),
 		New[int, int](size).ARC(),
+		New[int, int](size).TinyLFU(),
+		New[int, int](size).S3FIFO(),
+		New[int, int](size).Sieve(),
 	}
 	for _, builder := range testCaches {
 		var testCounter int64
@@ -160,6 +171,18 @@ func TestLoaderPurgeVisitorFunc(t *testing.T) {
 			name:         "arc",
 			cacheBuilder: New[int64, int64](size).ARC(),
 		},
+		{
+			name:         "tinylfu",
+			cacheBuilder: New[int64, int64](size).TinyLFU(),
+		},
+		{
+			name:         "s3fifo",
+			cacheBuilder: New[int64, int64](size).S3FIFO(),
+		},
+		{
+			name:         "sieve",
+			cacheBuilder: New[int64, int64](size).Sieve(),
+		},
 	}
 
 	for _, test := range tests {
@@ -215,6 +238,9 @@ func TestDeserializeFunc(t *testing.T) {
 		{TYPE_LRU},
 		{TYPE_LFU},
 		{TYPE_ARC},
+		{TYPE_TINYLFU},
+		{TYPE_S3FIFO},
+		{TYPE_SIEVE},
 	}
 
 	for _, cs := range cases {
@@ -274,6 +300,9 @@ func TestExpiredItems(t *testing.T) {
 		TYPE_LRU,
 		TYPE_LFU,
 		TYPE_ARC,
+		TYPE_TINYLFU,
+		TYPE_S3FIFO,
+		TYPE_SIEVE,
 	}
 	for _, tp := range tps {
 		t.Run(tp, func(t *testing.T) {
@@ -281,3 +310,1157 @@ func TestExpiredItems(t *testing.T) {
 		})
 	}
 }
+
+func TestMaximumWeight(t *testing.T) {
+	tps := []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_TINYLFU,
+		TYPE_S3FIFO,
+		TYPE_SIEVE,
+	}
+	for _, tp := range tps {
+		t.Run(tp, func(t *testing.T) {
+			var evicted int64
+			cache := New[int, string](8).
+				EvictType(tp).
+				Weigher(func(k int, v string) int64 {
+					return int64(len(v))
+				}).
+				MaximumWeight(100).
+				EvictedFunc(func(k int, v string) {
+					evicted += int64(len(v))
+				}).
+				Build()
+
+			var total int64
+			for i := 0; i < 100; i++ {
+				v := strings.Repeat("x", i%30+1)
+				if err := cache.Set(i, v); err != nil {
+					t.Fatal(err)
+				}
+				total += int64(len(v))
+			}
+			var weight int64
+			for _, v := range cache.GetALL(false) {
+				weight += int64(len(v))
+			}
+			if weight > 100 {
+				t.Errorf("total weight %v exceeds the maximum weight", weight)
+			}
+			if weight+evicted != total {
+				t.Errorf("%v + %v != %v", weight, evicted, total)
+			}
+
+			// growing an existing entry evicts others until it fits
+			cache.Set(1000, "x")
+			if err := cache.Set(1000, strings.Repeat("x", 100)); err != nil {
+				t.Fatal(err)
+			}
+			if keys := cache.Keys(false); len(keys) != 1 || keys[0] != 1000 {
+				t.Errorf("unexpected keys %v", keys)
+			}
+
+			err := cache.Set(2000, strings.Repeat("x", 101))
+			var tooHeavy *EntryTooHeavyError
+			if !errors.As(err, &tooHeavy) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tooHeavy.Weight != 101 || tooHeavy.MaximumWeight != 100 {
+				t.Errorf("unexpected error %v", tooHeavy)
+			}
+			if cache.Has(2000) {
+				t.Error("should not have 2000")
+			}
+		})
+	}
+}
+
+func TestMaximumWeightLightEntries(t *testing.T) {
+	tps := []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_TINYLFU,
+		TYPE_S3FIFO,
+		TYPE_SIEVE,
+	}
+	for _, tp := range tps {
+		t.Run(tp, func(t *testing.T) {
+			// far more entries than the size fit into the maximum weight
+			cache := New[int, int](2).
+				EvictType(tp).
+				MaximumWeight(100).
+				Build()
+			for i := 0; i < 1000; i++ {
+				if err := cache.Set(i%300, i); err != nil {
+					t.Fatal(err)
+				}
+				cache.Get(i % 150)
+			}
+			if l := cache.Len(false); l > 100 {
+				t.Errorf("%v entries exceed the maximum weight", l)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	tps := []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_TINYLFU,
+		TYPE_S3FIFO,
+		TYPE_SIEVE,
+	}
+	for _, tp := range tps {
+		t.Run(tp, func(t *testing.T) {
+			var evicted int
+			cache := New[int, int](10).
+				EvictType(tp).
+				EvictedFunc(func(k, v int) {
+					evicted++
+				}).
+				Build()
+
+			setItemsByRange(t, cache, 0, 10)
+			cache.Resize(5)
+			if l := cache.Len(false); l != 5 {
+				t.Fatalf("%v != 5", l)
+			}
+			if evicted != 5 {
+				t.Fatalf("%v != 5", evicted)
+			}
+
+			cache.Resize(20)
+			setItemsByRange(t, cache, 100, 115)
+			if l := cache.Len(false); l != 20 {
+				t.Fatalf("%v != 20", l)
+			}
+			if evicted != 5 {
+				t.Fatalf("%v != 5", evicted)
+			}
+		})
+	}
+}
+
+func TestResizeWeighted(t *testing.T) {
+	var evicted int
+	gc := New[int, int](10).
+		LRU().
+		MaximumWeight(100).
+		Weigher(func(_ int, v int) int64 {
+			return int64(v)
+		}).
+		EvictedFunc(func(int, int) {
+			evicted++
+		}).
+		Build()
+	for i := 0; i < 10; i++ {
+		gc.Set(i, 10)
+	}
+
+	// the new size is the maximum weight
+	gc.Resize(50)
+	if l := gc.Len(false); l != 5 {
+		t.Fatalf("%v != 5", l)
+	}
+	if evicted != 5 {
+		t.Fatalf("%v != 5", evicted)
+	}
+	for i := 5; i < 10; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("recently used key %v should have been kept", i)
+		}
+	}
+	var tooHeavy *EntryTooHeavyError
+	if err := gc.Set(10, 60); !errors.As(err, &tooHeavy) || tooHeavy.MaximumWeight != 50 {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	gc.Resize(200)
+	for i := 10; i < 30; i++ {
+		gc.Set(i, 10)
+	}
+	if l := gc.Len(false); l != 20 {
+		t.Fatalf("%v != 20", l)
+	}
+}
+
+func TestExpireAfterAccess(t *testing.T) {
+	tps := []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_TINYLFU,
+		TYPE_S3FIFO,
+		TYPE_SIEVE,
+	}
+	for _, tp := range tps {
+		t.Run(tp, func(t *testing.T) {
+			clock := NewFakeClock()
+			cache := New[int, int](10).
+				EvictType(tp).
+				Clock(clock).
+				ExpireAfterAccess(time.Minute).
+				Build()
+			cache.Set(1, 1)
+			cache.Set(2, 2)
+			cache.SetWithExpire(3, 3, 90*time.Second)
+
+			// reading 1 and 3 keeps them alive, 2 is idle
+			for i := 0; i < 3; i++ {
+				clock.Advance(40 * time.Second)
+				if _, err := cache.Get(1); err != nil {
+					t.Fatalf("1: %v", err)
+				}
+				if _, err := cache.GetIFPresent(3); i < 2 && err != nil {
+					t.Fatalf("3: %v", err)
+				}
+			}
+			if cache.Has(2) {
+				t.Error("2 should have expired")
+			}
+			// the expiration after write of 3 is not extended
+			if cache.Has(3) {
+				t.Error("3 should have expired")
+			}
+			// Has does not count as an access
+			clock.Advance(40 * time.Second)
+			cache.Has(1)
+			clock.Advance(40 * time.Second)
+			if _, err := cache.Get(1); err != KeyNotFoundError {
+				t.Errorf("1 should have expired, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExpireAfterAccessWithExpiration(t *testing.T) {
+	clock := NewFakeClock()
+	cache := New[int, int](10).
+		LRU().
+		Clock(clock).
+		Expiration(2 * time.Minute).
+		ExpireAfterAccess(time.Minute).
+		Build()
+	cache.Set(1, 1)
+	cache.Set(2, 2)
+	for i := 0; i < 3; i++ {
+		clock.Advance(50 * time.Second)
+		cache.Get(1)
+	}
+	if cache.Has(1) {
+		t.Error("1 should have expired after write")
+	}
+	if cache.Len(true) != 0 {
+		t.Error("2 should have expired after access")
+	}
+}
+
+func TestRefreshAfterWrite(t *testing.T) {
+	clock := NewFakeClock()
+	var loads int64
+	var fail atomic.Bool
+	release := make(chan struct{}, 10)
+	gc := New[int, int64](10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Hour).
+		RefreshAfterWrite(time.Minute).
+		LoaderFunc(func(_ context.Context, key int) (int64, error) {
+			n := atomic.AddInt64(&loads, 1)
+			if n > 1 {
+				<-release
+			}
+			if fail.Load() {
+				return 0, errors.New("failed")
+			}
+			return n, nil
+		}).
+		Build()
+
+	if v, err := gc.Get(1); err != nil || v != 1 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	clock.Advance(2 * time.Minute)
+	// the stale value is served while a single reload runs
+	for i := 0; i < 5; i++ {
+		if v, err := gc.Get(1); err != nil || v != 1 {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+	}
+	release <- struct{}{}
+	waitFor(t, func() bool {
+		v, _ := gc.GetIFPresent(1)
+		return v == 2
+	})
+	if l := atomic.LoadInt64(&loads); l != 2 {
+		t.Fatalf("%v != 2", l)
+	}
+
+	// a failed reload keeps the current value
+	fail.Store(true)
+	clock.Advance(2 * time.Minute)
+	gc.Get(1)
+	release <- struct{}{}
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(&loads) == 3
+	})
+	time.Sleep(10 * time.Millisecond)
+	if v, err := gc.Get(1); err != nil || v != 2 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	close(release)
+}
+
+func TestRefreshAfterWriteConcurrentWrite(t *testing.T) {
+	for _, tc := range []string{"set", "remove"} {
+		t.Run(tc, func(t *testing.T) {
+			clock := NewFakeClock()
+			var loads atomic.Int64
+			started, release := make(chan struct{}), make(chan struct{})
+			gc := New[int, int](10).
+				LRU().
+				Clock(clock).
+				RefreshAfterWrite(time.Minute).
+				LoaderFunc(func(_ context.Context, key int) (int, error) {
+					n := loads.Add(1)
+					if n > 1 {
+						close(started)
+						<-release
+					}
+					return int(n), nil
+				}).
+				Build().(*LRUCache[int, int])
+
+			gc.Get(1)
+			clock.Advance(2 * time.Minute)
+			gc.Get(1)
+			<-started
+			if tc == "set" {
+				gc.Set(1, 100)
+			} else {
+				gc.Remove(1)
+			}
+			close(release)
+			waitFor(t, func() bool {
+				gc.loadGroup.mu.Lock()
+				defer gc.loadGroup.mu.Unlock()
+				return len(gc.loadGroup.m) == 0
+			})
+
+			// the reload started before the write must not undo it
+			if tc == "set" {
+				if v, err := gc.GetIFPresent(1); err != nil || v != 100 {
+					t.Fatalf("unexpected %v, %v", v, err)
+				}
+			} else if gc.Has(1) {
+				t.Fatal("removed key restored by the reload")
+			}
+		})
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	clock := NewFakeClock()
+	var fail atomic.Bool
+	var loads int64
+	gc := New[int, int64](10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Minute).
+		StaleIfError(time.Hour).
+		LoaderFunc(func(_ context.Context, key int) (int64, error) {
+			n := atomic.AddInt64(&loads, 1)
+			if fail.Load() {
+				if key == 2 {
+					panic("down")
+				}
+				return 0, errors.New("down")
+			}
+			return n, nil
+		}).
+		Build()
+
+	gc.Get(1)
+	gc.Get(2)
+	gc.SetWithExpire(3, 3, 10*time.Hour)
+	fail.Store(true)
+	clock.Advance(2 * time.Minute)
+
+	if l := gc.Len(true); l != 1 {
+		t.Fatalf("%v != 1", l)
+	}
+	if keys := gc.Keys(true); len(keys) != 1 || keys[0] != 3 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	if l := gc.Len(false); l != 3 {
+		t.Fatalf("%v != 3", l)
+	}
+
+	for key, want := range map[int]int64{1: 1, 2: 2} {
+		v, err := gc.Get(key)
+		var stale *StaleValueError
+		if !errors.As(err, &stale) || v != want {
+			t.Fatalf("%v: unexpected %v, %v", key, v, err)
+		}
+	}
+	if _, err := gc.GetIFPresent(1); err != KeyNotFoundError {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// a successful load replaces the stale value
+	fail.Store(false)
+	if v, err := gc.Get(1); err != nil || v <= 2 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	if l := gc.Len(true); l != 2 {
+		t.Fatalf("%v != 2", l)
+	}
+
+	// after the grace period the error is returned
+	fail.Store(true)
+	clock.Advance(2 * time.Hour)
+	if _, err := gc.Get(2); err == nil || errors.As(err, new(*StaleValueError)) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if keys := gc.Keys(false); len(keys) != 2 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	if keys := gc.Keys(true); len(keys) != 1 || keys[0] != 3 {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestStaleIfErrorNegativeTTL(t *testing.T) {
+	clock := NewFakeClock()
+	errDown := errors.New("down")
+	var fail atomic.Bool
+	var loads int64
+	gc := New[int, int](10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Minute).
+		StaleIfError(time.Hour).
+		NegativeTTL(time.Minute, func(error) bool { return true }).
+		LoaderFunc(func(_ context.Context, key int) (int, error) {
+			atomic.AddInt64(&loads, 1)
+			if fail.Load() {
+				return 0, errDown
+			}
+			return key, nil
+		}).
+		Build()
+
+	gc.Get(1)
+	fail.Store(true)
+	clock.Advance(2 * time.Minute)
+
+	// the cached error is served with the stale value like a failed load
+	for i := 0; i < 3; i++ {
+		v, err := gc.Get(1)
+		var stale *StaleValueError
+		if !errors.As(err, &stale) || stale.Err != errDown || v != 1 {
+			t.Fatalf("%v: unexpected %v, %v", i, v, err)
+		}
+	}
+	if l := atomic.LoadInt64(&loads); l != 2 {
+		t.Fatalf("%v != 2", l)
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	clock := NewFakeClock()
+	errTemporary := errors.New("temporary")
+	var loads, added int64
+	gc := New[int, int](10).
+		LRU().
+		Clock(clock).
+		NegativeTTL(time.Minute, nil).
+		AddedFunc(func(int, int) {
+			added++
+		}).
+		LoaderFunc(func(_ context.Context, key int) (int, error) {
+			atomic.AddInt64(&loads, 1)
+			switch key {
+			case 1:
+				return 0, fmt.Errorf("user %v: %w", key, KeyNotFoundError)
+			case 2:
+				return 0, errTemporary
+			}
+			return key, nil
+		}).
+		Build()
+
+	for i := 0; i < 3; i++ {
+		if _, err := gc.Get(1); !errors.Is(err, KeyNotFoundError) {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if _, err := gc.Get(2); err != errTemporary {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	// only errors accepted by the classifier are cached
+	if l := atomic.LoadInt64(&loads); l != 4 {
+		t.Fatalf("%v != 4", l)
+	}
+	if n := gc.NegativeHitCount(); n != 2 {
+		t.Fatalf("%v != 2", n)
+	}
+	if added != 0 {
+		t.Fatalf("%v != 0", added)
+	}
+	if l := gc.Len(false); l != 0 {
+		t.Fatalf("%v != 0", l)
+	}
+
+	clock.Advance(2 * time.Minute)
+	gc.Get(1)
+	if l := atomic.LoadInt64(&loads); l != 5 {
+		t.Fatalf("%v != 5", l)
+	}
+
+	// setting a value replaces the cached error
+	gc.Set(1, 100)
+	if v, err := gc.Get(1); err != nil || v != 100 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
+
+func TestEarlyRefresh(t *testing.T) {
+	clock := NewFakeClock()
+	var loads int64
+	gc := New[int, int64](10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Hour).
+		EarlyRefresh(1).
+		LoaderFunc(func(_ context.Context, key int) (int64, error) {
+			// the load takes a second
+			clock.Advance(time.Second)
+			return atomic.AddInt64(&loads, 1), nil
+		}).
+		Build()
+
+	gc.Get(1)
+	// far from the expiration time, nothing is reloaded
+	for i := 0; i < 100; i++ {
+		if v, err := gc.Get(1); err != nil || v != 1 {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if l := atomic.LoadInt64(&loads); l != 1 {
+		t.Fatalf("%v != 1", l)
+	}
+
+	// a second before the expiration time, a reload is likely
+	clock.Advance(time.Hour - 2*time.Second)
+	for i := 0; i < 100 && atomic.LoadInt64(&loads) == 1; i++ {
+		if _, err := gc.Get(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, func() bool {
+		v, _ := gc.GetIFPresent(1)
+		return v == 2
+	})
+}
+
+func TestGetMany(t *testing.T) {
+	var bulkCalls [][]int
+	var mu sync.Mutex
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gc := New[int, int](100).
+		LRU().
+		LoaderFunc(func(_ context.Context, key int) (int, error) {
+			close(started)
+			<-release
+			return key * 100, nil
+		}).
+		BulkLoaderFunc(func(_ context.Context, keys []int) (map[int]int, error) {
+			mu.Lock()
+			bulkCalls = append(bulkCalls, keys)
+			mu.Unlock()
+			values := make(map[int]int, len(keys))
+			for _, key := range keys {
+				if key%2 == 0 {
+					values[key] = key * 10
+				}
+			}
+			return values, nil
+		}).
+		Build()
+	gc.Set(1, 1)
+
+	// 5 is being loaded by Get and should be waited on
+	go gc.Get(5)
+	<-started
+	done := make(chan map[int]int)
+	go func() {
+		values, err := gc.GetMany(context.Background(), []int{1, 2, 3, 4, 5, 4})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- values
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	values := <-done
+
+	want := map[int]int{1: 1, 2: 20, 4: 40, 5: 500}
+	if len(values) != len(want) {
+		t.Fatalf("unexpected values %v", values)
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Fatalf("unexpected values %v", values)
+		}
+	}
+	if len(bulkCalls) != 1 || len(bulkCalls[0]) != 3 {
+		t.Fatalf("unexpected bulk calls %v", bulkCalls)
+	}
+	if v, err := gc.GetIFPresent(4); err != nil || v != 40 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	// all keys are cached now, except for 3 which is not found
+	values, err := gc.GetMany(context.Background(), []int{1, 2, 4, 5})
+	if err != nil || len(values) != 4 || len(bulkCalls) != 1 {
+		t.Fatalf("unexpected %v, %v", values, err)
+	}
+}
+
+func TestGetManyError(t *testing.T) {
+	gc := New[int, int](100).
+		LRU().
+		BulkLoaderFunc(func(_ context.Context, keys []int) (map[int]int, error) {
+			panic("down")
+		}).
+		Build()
+	if _, err := gc.GetMany(context.Background(), []int{1, 2}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// without any loader only the cached keys are returned
+	gc = New[int, int](100).LRU().Build()
+	gc.Set(1, 1)
+	values, err := gc.GetMany(context.Background(), []int{1, 2})
+	if err != nil || len(values) != 1 || values[1] != 1 {
+		t.Fatalf("unexpected %v, %v", values, err)
+	}
+
+	// a cached loader error leaves the key out like a missing one
+	gc = New[int, int](100).
+		LRU().
+		NegativeTTL(time.Minute, func(error) bool { return true }).
+		LoaderFunc(func(_ context.Context, key int) (int, error) {
+			if key == 2 {
+				return 0, errors.New("down")
+			}
+			return key, nil
+		}).
+		Build()
+	if _, err := gc.Get(2); err == nil {
+		t.Fatal("expected an error")
+	}
+	values, err = gc.GetMany(context.Background(), []int{1, 2, 3})
+	if err != nil || len(values) != 2 || values[1] != 1 || values[3] != 3 {
+		t.Fatalf("unexpected %v, %v", values, err)
+	}
+}
+
+func TestGetWithContextCancelAbandonedLoads(t *testing.T) {
+	cancelled := make(chan struct{})
+	gc := New[int, int](10).
+		LRU().
+		CancelAbandonedLoads().
+		LoaderFunc(func(ctx context.Context, key int) (int, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return 0, ctx.Err()
+		}).
+		Build()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := gc.GetWithContext(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("abandoned load not cancelled")
+	}
+}
+
+func TestLoaderTimeout(t *testing.T) {
+	gc := New[int, int](10).
+		LRU().
+		LoaderTimeout(10 * time.Millisecond).
+		LoaderFunc(func(ctx context.Context, key int) (int, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("loader context has no deadline")
+			}
+			if key == 1 {
+				return key, nil
+			}
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}).
+		Build()
+
+	if v, err := gc.Get(1); err != nil || v != 1 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	if _, err := gc.Get(2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDetachLoaderContext(t *testing.T) {
+	type traceKey struct{}
+	started, release := make(chan struct{}), make(chan struct{})
+	gc := New[int, string](10).
+		LRU().
+		DetachLoaderContext().
+		LoaderFunc(func(ctx context.Context, key int) (string, error) {
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			return ctx.Value(traceKey{}).(string), nil
+		}).
+		Build()
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), traceKey{}, "trace"))
+	first := make(chan error)
+	go func() {
+		_, err := gc.GetWithContext(ctx, 1)
+		first <- err
+	}()
+	<-started
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	close(release)
+	if v, err := gc.GetWithContext(context.Background(), 1); err != nil || v != "trace" {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
+
+func TestLoaderRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	var mu sync.Mutex
+	attempts := map[int]int{}
+	gc := New[int, int](10).
+		LRU().
+		LoaderRetry(3, time.Millisecond, func(err error) bool { return errors.Is(err, errTransient) }).
+		LoaderFunc(func(ctx context.Context, key int) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[key]++
+			if key > 0 && attempts[key] <= key {
+				return 0, errTransient
+			}
+			if key < 0 {
+				return 0, errors.New("fatal")
+			}
+			return key, nil
+		}).
+		Build()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := gc.Get(2); err != nil || v != 2 {
+				t.Errorf("unexpected %v, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := gc.Get(3); !errors.Is(err, errTransient) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := gc.Get(-1); err == nil {
+		t.Fatal("expected error")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts[2] != 3 || attempts[3] != 3 || attempts[-1] != 1 {
+		t.Fatalf("unexpected attempts %v", attempts)
+	}
+	if n := gc.RetryCount(); n != 4 {
+		t.Fatalf("%v != 4", n)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := NewFakeClock()
+	var calls int
+	var failing bool
+	gc := New[int, int](10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Minute).
+		StaleIfError(time.Hour).
+		CircuitBreaker(0.5, 3, time.Minute, 30*time.Second).
+		LoaderFunc(func(ctx context.Context, key int) (int, error) {
+			calls++
+			if failing {
+				return 0, errors.New("backend down")
+			}
+			return key, nil
+		}).
+		Build()
+
+	gc.Get(1)
+	clock.Advance(2 * time.Minute)
+	failing = true
+	for i := 2; i < 5; i++ {
+		if _, err := gc.Get(i); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if _, err := gc.Get(5); !errors.Is(err, ErrLoaderCircuitOpen) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	v, err := gc.Get(1)
+	var staleErr *StaleValueError
+	if v != 1 || !errors.As(err, &staleErr) || !errors.Is(err, ErrLoaderCircuitOpen) {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	if calls != 4 {
+		t.Fatalf("%v != 4", calls)
+	}
+
+	clock.Advance(30 * time.Second)
+	failing = false
+	if v, err := gc.Get(5); err != nil || v != 5 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	if v, err := gc.Get(6); err != nil || v != 6 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
+
+func TestCircuitBreakerCallbackPanic(t *testing.T) {
+	gc := New[int, int](10).
+		LRU().
+		CircuitBreaker(0.5, 2, time.Minute, time.Minute).
+		AddedFunc(func(int, int) {
+			panic("added")
+		}).
+		LoaderFunc(func(ctx context.Context, key int) (int, error) {
+			return key, nil
+		}).
+		Build()
+
+	// the loader succeeded, so the panics of the added func are not failures
+	for i := 0; i < 4; i++ {
+		if _, err := gc.Get(i); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if _, err := gc.Get(5); errors.Is(err, ErrLoaderCircuitOpen) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	loader := func(ctx context.Context, key int) (int, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return key, nil
+	}
+
+	t.Run("queue", func(t *testing.T) {
+		gc := New[int, int](100).
+			LRU().
+			MaxConcurrentLoads(3, true).
+			LoaderFunc(loader).
+			Build()
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(key int) {
+				defer wg.Done()
+				if v, err := gc.Get(key); err != nil || v != key {
+					t.Errorf("unexpected %v, %v", v, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if maxRunning != 3 {
+			t.Fatalf("%v concurrent loads", maxRunning)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		gc := New[int, int](100).
+			LRU().
+			MaxConcurrentLoads(1, false).
+			LoaderFunc(func(ctx context.Context, key int) (int, error) {
+				if key == 1 {
+					close(started)
+					<-release
+				}
+				return key, nil
+			}).
+			Build()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			gc.Get(1)
+		}()
+		<-started
+		if _, err := gc.Get(2); !errors.Is(err, ErrTooManyLoads) {
+			t.Fatalf("unexpected error %v", err)
+		}
+		close(release)
+		<-done
+		if v, err := gc.Get(2); err != nil || v != 2 {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+	})
+}
+
+func TestHedgeLoads(t *testing.T) {
+	var calls int32
+	gc := New[int, int](10).
+		LRU().
+		HedgeLoads(10*time.Millisecond, 0.99).
+		LoaderFunc(func(ctx context.Context, key int) (int, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return key, nil
+		}).
+		Build()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := gc.Get(1); err != nil || v != 1 {
+				t.Errorf("unexpected %v, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("%v != 2", n)
+	}
+}
+
+func TestHedgeLoadsLimited(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning, calls int
+	loader := func(ctx context.Context, key int) (int, error) {
+		mu.Lock()
+		calls++
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(30 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return key, nil
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		gc := New[int, int](10).
+			LRU().
+			MaxConcurrentLoads(1, true).
+			HedgeLoads(5*time.Millisecond, 0).
+			LoaderFunc(loader).
+			Build()
+		if v, err := gc.Get(1); err != nil || v != 1 {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+		if maxRunning != 1 || calls != 1 {
+			t.Fatalf("%v concurrent of %v calls", maxRunning, calls)
+		}
+	})
+
+	t.Run("probe", func(t *testing.T) {
+		clock := NewFakeClock()
+		failing := true
+		calls = 0
+		gc := New[int, int](10).
+			LRU().
+			Clock(clock).
+			CircuitBreaker(0.5, 1, time.Minute, time.Minute).
+			HedgeLoads(5*time.Millisecond, 0).
+			LoaderFunc(func(ctx context.Context, key int) (int, error) {
+				if failing {
+					calls++
+					return 0, errors.New("backend down")
+				}
+				return loader(ctx, key)
+			}).
+			Build()
+		if _, err := gc.Get(1); err == nil {
+			t.Fatal("expected error")
+		}
+		clock.Advance(time.Minute)
+		failing = false
+		if v, err := gc.Get(1); err != nil || v != 1 {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+		if calls != 2 {
+			t.Fatalf("%v != 2", calls)
+		}
+	})
+}
+
+func TestLoaderChain(t *testing.T) {
+	clock := NewFakeClock()
+	primaryDown := true
+	gc := New[int, int](10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Hour).
+		LoaderChain(
+			LoaderStage[int, int]{Loader: func(ctx context.Context, key int) (int, error) {
+				if primaryDown {
+					return 0, errors.New("primary down")
+				}
+				return key, nil
+			}},
+			LoaderStage[int, int]{
+				Loader: func(ctx context.Context, key int) (int, error) {
+					return -key, nil
+				},
+				TTL: time.Minute,
+			},
+		).
+		Build()
+
+	if v, err := gc.Get(1); err != nil || v != -1 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	primaryDown = false
+	clock.Advance(2 * time.Minute)
+	if v, err := gc.Get(1); err != nil || v != 1 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	clock.Advance(2 * time.Minute)
+	if v, err := gc.Get(1); err != nil || v != 1 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	if gc.StageHitCount(0) != 1 || gc.StageHitCount(1) != 1 {
+		t.Fatalf("unexpected stage hit counts %v, %v", gc.StageHitCount(0), gc.StageHitCount(1))
+	}
+}
+
+func TestResultLoaderFunc(t *testing.T) {
+	clock := NewFakeClock()
+	var calls int
+	gc := New[int, int](10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Hour).
+		MaximumWeight(10).
+		ResultLoaderFunc(func(ctx context.Context, key int) (LoadResult[int], error) {
+			calls++
+			r := LoadResult[int]{Value: key, Tags: []string{"all"}}
+			switch key {
+			case 1:
+				r.TTL = time.Minute
+			case 2:
+				r.Weight = 5
+				r.Tags = append(r.Tags, "heavy")
+			case 3:
+				r.NoCache = true
+			}
+			return r, nil
+		}).
+		Build()
+
+	for key := 1; key <= 4; key++ {
+		if v, err := gc.Get(key); err != nil || v != key {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+	}
+	if gc.Has(3) {
+		t.Fatal("value with NoCache stored")
+	}
+	if v, err := gc.Get(3); err != nil || v != 3 || calls != 5 {
+		t.Fatalf("unexpected %v, %v after %v calls", v, err, calls)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if gc.Has(1) || !gc.Has(4) {
+		t.Fatal("TTL not applied")
+	}
+	for key := 5; key <= 9; key++ {
+		gc.Get(key)
+	}
+	if gc.Has(2) {
+		t.Fatal("weight not applied")
+	}
+
+	gc.Get(2)
+	if n := gc.RemoveByTag("heavy"); n != 1 || gc.Has(2) {
+		t.Fatalf("%v removed", n)
+	}
+	if n := gc.RemoveByTag("all"); n != 5 || gc.Len(false) != 0 {
+		t.Fatalf("%v removed, %v left", n, gc.Len(false))
+	}
+	if n := gc.RemoveByTag("heavy"); n != 0 {
+		t.Fatalf("%v removed", n)
+	}
+}
+
+func TestResultLoaderFuncVersion(t *testing.T) {
+	gc := New[int, int](10).LRU().Build().(*LRUCache[int, int])
+
+	gc.setLoaded(1, entryValue[int]{value: 2, version: 2}, 0, 0)
+	if v, err := gc.setLoaded(1, entryValue[int]{value: 1, version: 1}, 0, 0); err != nil || v != 1 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	if v, _ := gc.Get(1); v != 2 {
+		t.Fatalf("older version stored: %v", v)
+	}
+	gc.setLoaded(1, entryValue[int]{value: 3, version: 3}, 0, 0)
+	if v, _ := gc.Get(1); v != 3 {
+		t.Fatalf("newer version not stored: %v", v)
+	}
+}
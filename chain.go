@@ -0,0 +1,54 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// LoaderStage is a stage of a loader chain set with LoaderChain.
+type LoaderStage[K comparable, V any] struct {
+	Loader LoaderFunc[K, V]
+	// Timeout limits a call of Loader, after which the chain moves on to the
+	// next stage. Zero means no limit.
+	Timeout time.Duration
+	// TTL is the expiration of the values loaded by this stage. Zero means
+	// the expiration of the cache.
+	TTL time.Duration
+}
+
+// chainLoader returns a loader calling the stages in order until one of them
+// succeeds, and counting the values loaded by each stage in st. A stage
+// returning KeyNotFoundError ends the chain, as does the context of the load
+// being done. If all stages fail, their errors are joined.
+func chainLoader[K comparable, V any](stages []LoaderStage[K, V], st *stats) LoaderExpireFunc[K, V] {
+	return func(ctx context.Context, key K) (V, *time.Duration, error) {
+		var errs []error
+		for i, stage := range stages {
+			v, err := loadStage(ctx, key, stage)
+			if err == nil {
+				st.IncrStageHitCount(i)
+				if stage.TTL > 0 {
+					ttl := stage.TTL
+					return v, &ttl, nil
+				}
+				return v, nil, nil
+			}
+			errs = append(errs, err)
+			if errors.Is(err, KeyNotFoundError) || ctx.Err() != nil {
+				break
+			}
+		}
+		var v V
+		return v, nil, errors.Join(errs...)
+	}
+}
+
+func loadStage[K comparable, V any](ctx context.Context, key K, stage LoaderStage[K, V]) (V, error) {
+	if stage.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stage.Timeout)
+		defer cancel()
+	}
+	return stage.Loader(ctx, key)
+}
@@ -0,0 +1,67 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChainLoader(t *testing.T) {
+	errDown := errors.New("down")
+	var secondaryCalls int
+	st := &stats{stageHitCounts: make([]uint64, 2)}
+	load := chainLoader([]LoaderStage[int, int]{
+		{
+			Loader: func(ctx context.Context, key int) (int, error) {
+				switch key {
+				case 1:
+					return 1, nil
+				case 2, 5:
+					return 0, errDown
+				case 3:
+					<-ctx.Done()
+					return 0, ctx.Err()
+				}
+				return 0, KeyNotFoundError
+			},
+			Timeout: 10 * time.Millisecond,
+		},
+		{
+			Loader: func(ctx context.Context, key int) (int, error) {
+				secondaryCalls++
+				if key == 5 {
+					return 0, errors.New("down too")
+				}
+				return key * 10, nil
+			},
+			TTL: time.Minute,
+		},
+	}, st)
+
+	if v, exp, err := load(context.Background(), 1); v != 1 || exp != nil || err != nil {
+		t.Fatalf("unexpected %v, %v, %v", v, exp, err)
+	}
+	for _, key := range []int{2, 3} {
+		if v, exp, err := load(context.Background(), key); v != key*10 || exp == nil || *exp != time.Minute || err != nil {
+			t.Fatalf("unexpected %v, %v, %v", v, exp, err)
+		}
+	}
+	if _, _, err := load(context.Background(), 4); !errors.Is(err, KeyNotFoundError) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, _, err := load(context.Background(), 5); !errors.Is(err, errDown) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := load(ctx, 3); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if secondaryCalls != 3 {
+		t.Fatalf("%v != 3", secondaryCalls)
+	}
+	if st.StageHitCount(0) != 1 || st.StageHitCount(1) != 2 || st.StageHitCount(2) != 0 {
+		t.Fatalf("unexpected stage hit counts %v", st.stageHitCounts)
+	}
+}
@@ -0,0 +1,30 @@
+package gcache
+
+import (
+	"math"
+	"time"
+)
+
+// NoExpiration is the duration of an item which never expires.
+const NoExpiration time.Duration = math.MaxInt64
+
+// Expiry computes how long items live. Each method returns the duration from
+// now until the item expires, or NoExpiration. The remaining time is the
+// duration until the current expiration time of the item, or NoExpiration if
+// it has none; returning it keeps the expiration time unchanged.
+//
+// The value is the value as stored in the cache, after SerializeFunc has been
+// applied. The methods are called while the cache is locked and must not call
+// the cache.
+//
+// An expiration passed to SetWithExpire or returned by a LoaderExpireFunc
+// takes precedence over ExpireAfterCreate and ExpireAfterUpdate for that
+// write. An Expiry cannot be combined with Expiration or ExpireAfterAccess.
+type Expiry[K comparable, V any] interface {
+	// ExpireAfterCreate is called when key is added to the cache.
+	ExpireAfterCreate(key K, value V) time.Duration
+	// ExpireAfterUpdate is called when the value of key is replaced.
+	ExpireAfterUpdate(key K, value V, remaining time.Duration) time.Duration
+	// ExpireAfterRead is called when key is read by Get or GetIFPresent.
+	ExpireAfterRead(key K, value V, remaining time.Duration) time.Duration
+}
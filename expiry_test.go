@@ -0,0 +1,122 @@
+package gcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type token struct {
+	exp time.Time
+}
+
+// tokenExpiry lets tokens live until their exp claim and extends the lifetime
+// of tokens without claim by a minute on every read.
+type tokenExpiry struct {
+	clock Clock
+}
+
+func (e tokenExpiry) ExpireAfterCreate(key string, value token) time.Duration {
+	if value.exp.IsZero() {
+		return NoExpiration
+	}
+	return value.exp.Sub(e.clock.Now())
+}
+
+func (e tokenExpiry) ExpireAfterUpdate(key string, value token, remaining time.Duration) time.Duration {
+	return e.ExpireAfterCreate(key, value)
+}
+
+func (e tokenExpiry) ExpireAfterRead(key string, value token, remaining time.Duration) time.Duration {
+	if value.exp.IsZero() && remaining != NoExpiration {
+		return time.Minute
+	}
+	return remaining
+}
+
+func TestExpiry(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New[string, token](10).
+		LRU().
+		Clock(clock).
+		Expiry(tokenExpiry{clock}).
+		Build()
+
+	gc.Set("a", token{exp: clock.Now().Add(time.Hour)})
+	gc.Set("b", token{exp: clock.Now().Add(2 * time.Hour)})
+	gc.Set("forever", token{})
+	// an explicit expiration takes precedence
+	gc.SetWithExpire("c", token{}, 30*time.Second)
+
+	clock.Advance(20 * time.Second)
+	if _, err := gc.Get("c"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(50 * time.Second)
+	if !gc.Has("c") {
+		t.Error("reading c should have extended its lifetime")
+	}
+
+	clock.Advance(time.Hour)
+	if gc.Has("a") {
+		t.Error("a should have expired")
+	}
+	if !gc.Has("b") {
+		t.Error("b should not have expired")
+	}
+	if gc.Has("c") {
+		t.Error("c should have expired")
+	}
+	gc.Set("b", token{exp: clock.Now().Add(time.Second)})
+	clock.Advance(2 * time.Second)
+	if gc.Has("b") {
+		t.Error("b should have expired after update")
+	}
+
+	clock.Advance(24 * time.Hour)
+	if _, err := gc.Get("forever"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExpiryLoaderExpiration(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New[string, token](10).
+		LRU().
+		Clock(clock).
+		Expiry(tokenExpiry{clock}).
+		LoaderExpireFunc(func(_ context.Context, key string) (token, *time.Duration, error) {
+			if key == "ttl" {
+				ttl := time.Second
+				return token{}, &ttl, nil
+			}
+			return token{exp: clock.Now().Add(time.Minute)}, nil, nil
+		}).
+		Build()
+
+	gc.Get("ttl")
+	gc.Get("exp")
+	clock.Advance(2 * time.Second)
+	if gc.Has("ttl") {
+		t.Error("the loader expiration should take precedence")
+	}
+	if !gc.Has("exp") {
+		t.Error("exp should not have expired")
+	}
+	clock.Advance(time.Minute)
+	if gc.Has("exp") {
+		t.Error("exp should have expired")
+	}
+}
+
+func TestExpiryWithExpiration(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Build should panic")
+		}
+	}()
+	New[string, token](10).
+		Expiry(tokenExpiry{}).
+		Expiration(time.Minute).
+		Build()
+}
@@ -0,0 +1,19 @@
+package gcache
+
+import (
+	"hash/maphash"
+)
+
+// hasher hashes keys of any comparable type using a random seed.
+type hasher[K comparable] struct {
+	seed maphash.Seed
+}
+
+func newHasher[K comparable]() hasher[K] {
+	return hasher[K]{seed: maphash.MakeSeed()}
+}
+
+// Hash returns the hash of key.
+func (h hasher[K]) Hash(key K) uint64 {
+	return maphash.Comparable(h.seed, key)
+}
@@ -0,0 +1,136 @@
+package gcache
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// hedgeSamples is the number of recent load latencies kept to compute
+	// the hedging percentile.
+	hedgeSamples = 128
+	// hedgeMinSamples is the number of latencies needed before the
+	// percentile replaces the fixed delay.
+	hedgeMinSamples = 16
+)
+
+// hedger decides when a slow loader call gets a second, hedged call.
+type hedger struct {
+	delay      time.Duration
+	percentile float64
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func newHedger(delay time.Duration, percentile float64) *hedger {
+	return &hedger{delay: delay, percentile: percentile}
+}
+
+// instance returns a hedger with the configuration of h. A hedger built by
+// the CacheBuilder only holds the configuration and gets a new instance for
+// every cache, while an instance is returned as it is.
+func (h *hedger) instance() *hedger {
+	if h == nil || h.samples != nil {
+		return h
+	}
+	h = newHedger(h.delay, h.percentile)
+	h.samples = make([]time.Duration, 0, hedgeSamples)
+	return h
+}
+
+// after returns the delay after which a call is hedged: the percentile of the
+// recent latencies, or the fixed delay until enough latencies are known.
+func (h *hedger) after() time.Duration {
+	if h.percentile <= 0 {
+		return h.delay
+	}
+	h.mu.Lock()
+	if len(h.samples) < hedgeMinSamples {
+		h.mu.Unlock()
+		return h.delay
+	}
+	sorted := slices.Clone(h.samples)
+	h.mu.Unlock()
+	slices.Sort(sorted)
+	return sorted[int(h.percentile*float64(len(sorted)-1))]
+}
+
+// observe records the latency of a successful call.
+func (h *hedger) observe(d time.Duration) {
+	if h.percentile <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % hedgeSamples
+}
+
+// hedge calls fn, and calls it a second time if the first call has not
+// returned after the hedging delay. The second call takes a slot of l of its
+// own and is skipped if there is none. The first successful result wins and
+// the context of the other call is cancelled; if both calls fail, the last
+// error is returned. A panicking call is turned into an error. A nil hedger
+// calls fn once.
+func hedge[T any](ctx context.Context, h *hedger, l *loadLimiter, fn func(context.Context) (T, error)) (T, error) {
+	if h == nil {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		v       T
+		err     error
+		latency time.Duration
+	}
+	results := make(chan result, 2)
+	call := func() {
+		start := time.Now()
+		var r result
+		defer func() {
+			if p := recover(); p != nil {
+				r.err = fmt.Errorf("loader panics: %v", p)
+			}
+			r.latency = time.Since(start)
+			results <- r
+		}()
+		r.v, r.err = fn(ctx)
+	}
+
+	go call()
+	timer := time.NewTimer(h.after())
+	defer timer.Stop()
+	var r result
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer.C:
+			if !l.tryAcquire() {
+				continue
+			}
+			pending++
+			go func() {
+				defer l.release()
+				call()
+			}()
+		case r = <-results:
+			pending--
+			if r.err == nil {
+				h.observe(r.latency)
+				return r.v, nil
+			}
+			// a call failing before the hedging delay is not hedged
+			timer.Stop()
+		}
+	}
+	return r.v, r.err
+}
@@ -0,0 +1,91 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgerPercentile(t *testing.T) {
+	h := newHedger(time.Second, 0.9).instance()
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if d := h.after(); d != time.Second {
+		t.Fatalf("%v != %v", d, time.Second)
+	}
+	for i := 0; i < 2*hedgeSamples; i++ {
+		h.observe(time.Duration(i%100+1) * time.Millisecond)
+	}
+	if d := h.after(); d < 85*time.Millisecond || d > 95*time.Millisecond {
+		t.Fatalf("p90 %v", d)
+	}
+	if n := len(h.samples); n != hedgeSamples {
+		t.Fatalf("%v != %v", n, hedgeSamples)
+	}
+}
+
+func TestHedge(t *testing.T) {
+	h := newHedger(10*time.Millisecond, 0).instance()
+
+	t.Run("hedged", func(t *testing.T) {
+		var calls int32
+		cancelled := make(chan struct{})
+		v, err := hedge(context.Background(), h, nil, func(ctx context.Context) (int, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				<-ctx.Done()
+				close(cancelled)
+				return 0, ctx.Err()
+			}
+			return 2, nil
+		})
+		if v != 2 || err != nil {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("slow call not cancelled")
+		}
+	})
+
+	t.Run("fast", func(t *testing.T) {
+		var calls int32
+		errFailed := errors.New("failed")
+		_, err := hedge(context.Background(), h, nil, func(ctx context.Context) (int, error) {
+			atomic.AddInt32(&calls, 1)
+			return 0, errFailed
+		})
+		time.Sleep(20 * time.Millisecond)
+		if err != errFailed || atomic.LoadInt32(&calls) != 1 {
+			t.Fatalf("%v after %v calls", err, calls)
+		}
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		// the first call holds the only slot
+		l := newLoadLimiter(1, true)
+		l.acquire(context.Background())
+		defer l.release()
+		var calls int32
+		v, err := hedge(context.Background(), h, l, func(ctx context.Context) (int, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(30 * time.Millisecond)
+			return 1, nil
+		})
+		if v != 1 || err != nil || atomic.LoadInt32(&calls) != 1 {
+			t.Fatalf("unexpected %v, %v after %v calls", v, err, calls)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		_, err := hedge(context.Background(), h, nil, func(ctx context.Context) (int, error) {
+			panic("boom")
+		})
+		if err == nil || err.Error() != "loader panics: boom" {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package gcache
+
+import (
+	"sync"
+	"time"
+)
+
+// cleanupBatchSize is the number of items of the timer wheel visited by a
+// cleanup per lock acquisition.
+const cleanupBatchSize = 64
+
+// janitor periodically runs a cleanup function in the background until it is
+// stopped.
+type janitor struct {
+	stop chan struct{}
+	once sync.Once
+	done sync.WaitGroup
+}
+
+func startJanitor(interval time.Duration, cleanup func()) *janitor {
+	j := &janitor{stop: make(chan struct{})}
+	j.done.Add(1)
+	go func() {
+		defer j.done.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cleanup()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+	return j
+}
+
+// Stop stops the janitor and waits for a running cleanup to finish. It is
+// safe to call Stop more than once.
+func (j *janitor) Stop() {
+	j.once.Do(func() {
+		close(j.stop)
+	})
+	j.done.Wait()
+}
@@ -0,0 +1,111 @@
+package gcache
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCleanupInterval(t *testing.T) {
+	var evicted int64
+	gc := New[int, int](1000).
+		LRU().
+		Expiration(time.Millisecond).
+		CleanupInterval(5 * time.Millisecond).
+		EvictedFunc(func(k, v int) {
+			atomic.AddInt64(&evicted, 1)
+		}).
+		Build()
+	defer gc.Close()
+
+	setItemsByRange(t, gc, 0, 500)
+	gc.SetWithExpire(1000, 1000, time.Hour)
+
+	deadline := time.Now().Add(time.Second)
+	for gc.Len(false) > 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if l := gc.Len(false); l != 1 {
+		t.Fatalf("%v != 1", l)
+	}
+	if !gc.Has(1000) {
+		t.Fatal("should have 1000")
+	}
+	if e := atomic.LoadInt64(&evicted); e != 500 {
+		t.Fatalf("%v != 500", e)
+	}
+}
+
+func TestCleanupFakeClock(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New[int, int](10000).
+		Simple().
+		Clock(clock).
+		Build().(*SimpleCache[int, int])
+
+	for i := 0; i < 5000; i++ {
+		gc.SetWithExpire(i, i, time.Duration(i)*time.Millisecond)
+	}
+	for i := 5000; i < 10000; i++ {
+		gc.Set(i, i)
+	}
+
+	clock.Advance(2500 * time.Millisecond)
+	gc.cleanup()
+	if l := gc.Len(false); l != 7500 {
+		t.Fatalf("%v != 7500", l)
+	}
+	clock.Advance(time.Hour)
+	gc.cleanup()
+	if l := gc.Len(false); l != 5000 {
+		t.Fatalf("%v != 5000", l)
+	}
+}
+
+func TestCleanupBatched(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New[int, int](10000).
+		Simple().
+		Clock(clock).
+		Build().(*SimpleCache[int, int])
+
+	for i := 0; i < 5000; i++ {
+		gc.SetWithExpire(i, i, time.Second)
+	}
+	for i := 5000; i < 10000; i++ {
+		gc.Set(i, i)
+	}
+	clock.Advance(2 * time.Second)
+
+	// a single lock acquisition expires at most a batch of items
+	gc.mu.Lock()
+	done := gc.expire(clock.Now(), cleanupBatchSize)
+	gc.mu.Unlock()
+	if l := gc.Len(false); done || l < 10000-cleanupBatchSize || l >= 10000 {
+		t.Fatalf("%v items left", l)
+	}
+	gc.cleanup()
+	if l := gc.Len(false); l != 5000 {
+		t.Fatalf("%v != 5000", l)
+	}
+}
+
+func TestClose(t *testing.T) {
+	gc := New[int, int](10).
+		LRU().
+		CleanupInterval(time.Millisecond).
+		Shards(2).
+		Build()
+	gc.Close()
+	gc.Close()
+
+	if err := gc.Set(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := gc.Get(1); err != nil || v != 1 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	// Close is a no-op without a cleanup interval
+	New[int, int](10).LRU().Build().Close()
+}
@@ -2,213 +2,56 @@ package gcache
 
 import (
 	"container/list"
-	"context"
-	"errors"
-	"time"
 )
 
 // LFUCache Discards the least frequently used items first.
 type LFUCache[K comparable, V any] struct {
-	baseCache[K, V]
-	items    map[K]*lfuItem[K, V]
-	freqList *list.List // list for freqEntry
+	policyCache[K, V]
 }
 
 var _ Cache[int, int] = (*LFUCache[int, int])(nil)
 
-type lfuItem[K comparable, V any] struct {
-	clock       Clock
-	key         K
-	value       V
-	freqElement *list.Element
-	expiration  *time.Time
-}
-
-type freqEntry[K comparable, V any] struct {
-	freq  uint
-	items map[*lfuItem[K, V]]struct{}
-}
-
 func newLFUCache[K comparable, V any](cb *CacheBuilder[K, V]) *LFUCache[K, V] {
 	c := &LFUCache[K, V]{}
-	buildCache(&c.baseCache, cb)
-
-	c.init()
-	c.loadGroup.cache = c
+	buildPolicyCache(&c.policyCache, cb, newLFUPolicy[K](cb.size))
 	return c
 }
 
-func (c *LFUCache[K, V]) init() {
-	c.freqList = list.New()
-	c.items = make(map[K]*lfuItem[K, V], c.size)
-	c.freqList.PushFront(&freqEntry[K, V]{
-		freq:  0,
-		items: make(map[*lfuItem[K, V]]struct{}),
-	})
-}
-
-// Set a new key-value pair
-func (c *LFUCache[K, V]) Set(key K, value V) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	_, err := c.set(key, value)
-	return err
-}
-
-// SetWithExpire Set a new key-value pair with an expiration time
-func (c *LFUCache[K, V]) SetWithExpire(key K, value V, expiration time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	item, err := c.set(key, value)
-	if err != nil {
-		return err
-	}
-
-	t := c.clock.Now().Add(expiration)
-	item.expiration = &t
-	return nil
+// lfuPolicy evicts the least frequently used key.
+type lfuPolicy[K comparable] struct {
+	size     int
+	items    map[K]*list.Element // element of the key's freqEntry
+	freqList *list.List          // list for freqEntry
 }
 
-func (c *LFUCache[K, V]) set(key K, value V) (*lfuItem[K, V], error) {
-	var err error
-	if c.serializeFunc != nil {
-		value, err = c.serializeFunc(key, value)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Check for existing item
-	item, ok := c.items[key]
-	if ok {
-		item.value = value
-	} else {
-		// Verify size not exceeded
-		if len(c.items) >= c.size {
-			c.evict(1)
-		}
-		item = &lfuItem[K, V]{
-			clock:       c.clock,
-			key:         key,
-			value:       value,
-			freqElement: nil,
-		}
-		el := c.freqList.Front()
-		fe := el.Value.(*freqEntry[K, V])
-		fe.items[item] = struct{}{}
-
-		item.freqElement = el
-		c.items[key] = item
-	}
-
-	if c.expiration != nil {
-		t := c.clock.Now().Add(*c.expiration)
-		item.expiration = &t
-	}
-
-	if c.addedFunc != nil {
-		c.addedFunc(key, value)
-	}
-
-	return item, nil
-}
-
-// Get a value from cache pool using key if it exists.
-// If it does not exists key and has LoaderFunc,
-// generate a value using `LoaderFunc` method returns value.
-func (c *LFUCache[K, V]) Get(key K) (V, error) {
-	return c.GetWithContext(context.Background(), key)
-}
-
-// GetIFPresent gets a value from cache pool using key if it exists.
-// If it does not exists key, returns KeyNotFoundError.
-// And send a request which refresh value for specified key if cache object has LoaderFunc.
-func (c *LFUCache[K, V]) GetIFPresent(key K) (V, error) {
-	return c.GetIFPresentWithContext(context.Background(), key)
-}
-
-func (c *LFUCache[K, V]) GetWithContext(ctx context.Context, key K) (V, error) {
-	v, err := c.get(key, false)
-	if errors.Is(err, KeyNotFoundError) {
-		return c.getWithLoader(ctx, key, true)
-	}
-	return v, err
+type freqEntry[K comparable] struct {
+	freq  uint
+	items map[K]struct{}
 }
 
-func (c *LFUCache[K, V]) GetIFPresentWithContext(ctx context.Context, key K) (V, error) {
-	v, err := c.get(key, false)
-	if errors.Is(err, KeyNotFoundError) {
-		return c.getWithLoader(ctx, key, false)
-	}
-	return v, err
+func newLFUPolicy[K comparable](size int) *lfuPolicy[K] {
+	p := &lfuPolicy[K]{size: size}
+	p.Reset()
+	return p
 }
 
-func (c *LFUCache[K, V]) get(key K, onLoad bool) (V, error) {
-	v, err := c.getValue(key, onLoad)
-	if err != nil {
-		var v V
-		return v, err
-	}
-	if c.deserializeFunc != nil {
-		return c.deserializeFunc(key, v)
-	}
-	return v, nil
+func (p *lfuPolicy[K]) OnInsert(key K) {
+	el := p.freqList.Front()
+	el.Value.(*freqEntry[K]).items[key] = struct{}{}
+	p.items[key] = el
 }
 
-func (c *LFUCache[K, V]) getValue(key K, onLoad bool) (v V, _ error) {
-	c.mu.Lock()
-	item, ok := c.items[key]
-	if ok {
-		if !item.IsExpired(nil) {
-			c.increment(item)
-			v := item.value
-			c.mu.Unlock()
-			if !onLoad {
-				c.stats.IncrHitCount()
-			}
-			return v, nil
-		}
-		c.removeItem(item)
-	}
-	c.mu.Unlock()
-	if !onLoad {
-		c.stats.IncrMissCount()
-	}
-	return v, KeyNotFoundError
-}
+// OnUpdate leaves the frequency of key unchanged, only reads count.
+func (p *lfuPolicy[K]) OnUpdate(key K) {}
 
-func (c *LFUCache[K, V]) getWithLoader(ctx context.Context, key K, isWait bool) (v V, _ error) {
-	if c.loaderExpireFunc == nil {
-		return v, KeyNotFoundError
-	}
-	value, _, err := c.load(ctx, key, func(v V, expiration *time.Duration, e error) (V, error) {
-		if e != nil {
-			return v, e
-		}
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		item, err := c.set(key, v)
-		if err != nil {
-			return v, err
-		}
-		if expiration != nil {
-			t := c.clock.Now().Add(*expiration)
-			item.expiration = &t
-		}
-		return v, nil
-	}, isWait)
-	if err != nil {
-		var v V
-		return v, err
+func (p *lfuPolicy[K]) OnAccess(key K) {
+	currentFreqElement, ok := p.items[key]
+	if !ok {
+		return
 	}
-	return value, nil
-}
-
-func (c *LFUCache[K, V]) increment(item *lfuItem[K, V]) {
-	currentFreqElement := item.freqElement
-	currentFreqEntry := currentFreqElement.Value.(*freqEntry[K, V])
+	currentFreqEntry := currentFreqElement.Value.(*freqEntry[K])
 	nextFreq := currentFreqEntry.freq + 1
-	delete(currentFreqEntry.items, item)
+	delete(currentFreqEntry.items, key)
 
 	// a boolean whether reuse the empty current entry
 	removable := isRemovableFreqEntry(currentFreqEntry)
@@ -216,174 +59,59 @@ func (c *LFUCache[K, V]) increment(item *lfuItem[K, V]) {
 	// insert item into a valid entry
 	nextFreqElement := currentFreqElement.Next()
 	switch {
-	case nextFreqElement == nil || nextFreqElement.Value.(*freqEntry[K, V]).freq > nextFreq:
+	case nextFreqElement == nil || nextFreqElement.Value.(*freqEntry[K]).freq > nextFreq:
 		if removable {
 			currentFreqEntry.freq = nextFreq
 			nextFreqElement = currentFreqElement
 		} else {
-			nextFreqElement = c.freqList.InsertAfter(&freqEntry[K, V]{
+			nextFreqElement = p.freqList.InsertAfter(&freqEntry[K]{
 				freq:  nextFreq,
-				items: make(map[*lfuItem[K, V]]struct{}),
+				items: make(map[K]struct{}),
 			}, currentFreqElement)
 		}
-	case nextFreqElement.Value.(*freqEntry[K, V]).freq == nextFreq:
+	case nextFreqElement.Value.(*freqEntry[K]).freq == nextFreq:
 		if removable {
-			c.freqList.Remove(currentFreqElement)
+			p.freqList.Remove(currentFreqElement)
 		}
 	default:
 		panic("unreachable")
 	}
-	nextFreqElement.Value.(*freqEntry[K, V]).items[item] = struct{}{}
-	item.freqElement = nextFreqElement
-}
-
-// evict removes the least frequence item from the cache.
-func (c *LFUCache[K, V]) evict(count int) {
-	entry := c.freqList.Front()
-	for i := 0; i < count; {
-		if entry == nil {
-			return
-		} else {
-			for item := range entry.Value.(*freqEntry[K, V]).items {
-				if i >= count {
-					return
-				}
-				c.removeItem(item)
-				i++
-			}
-			entry = entry.Next()
-		}
-	}
-}
-
-// Has checks if key exists in cache
-func (c *LFUCache[K, V]) Has(key K) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	now := time.Now()
-	return c.has(key, &now)
+	nextFreqElement.Value.(*freqEntry[K]).items[key] = struct{}{}
+	p.items[key] = nextFreqElement
 }
 
-func (c *LFUCache[K, V]) has(key K, now *time.Time) bool {
-	item, ok := c.items[key]
+func (p *lfuPolicy[K]) OnRemove(key K) {
+	el, ok := p.items[key]
 	if !ok {
-		return false
+		return
 	}
-	return !item.IsExpired(now)
-}
-
-// Remove removes the provided key from the cache.
-func (c *LFUCache[K, V]) Remove(key K) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	return c.remove(key)
-}
-
-func (c *LFUCache[K, V]) remove(key K) bool {
-	if item, ok := c.items[key]; ok {
-		c.removeItem(item)
-		return true
-	}
-	return false
-}
-
-// removeElement is used to remove a given list element from the cache
-func (c *LFUCache[K, V]) removeItem(item *lfuItem[K, V]) {
-	entry := item.freqElement.Value.(*freqEntry[K, V])
-	delete(c.items, item.key)
-	delete(entry.items, item)
+	entry := el.Value.(*freqEntry[K])
+	delete(p.items, key)
+	delete(entry.items, key)
 	if isRemovableFreqEntry(entry) {
-		c.freqList.Remove(item.freqElement)
-	}
-	if c.evictedFunc != nil {
-		c.evictedFunc(item.key, item.value)
+		p.freqList.Remove(el)
 	}
 }
 
-func (c *LFUCache[K, V]) keys() []K {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]K, len(c.items))
-	i := 0
-	for k := range c.items {
-		keys[i] = k
-		i++
-	}
-	return keys
-}
-
-// GetALL returns all key-value pairs in the cache.
-func (c *LFUCache[K, V]) GetALL(checkExpired bool) map[K]V {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	items := make(map[K]V, len(c.items))
-	now := time.Now()
-	for k, item := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			items[k] = item.value
-		}
-	}
-	return items
-}
-
-// Keys returns a slice of the keys in the cache.
-func (c *LFUCache[K, V]) Keys(checkExpired bool) []K {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]K, 0, len(c.items))
-	now := time.Now()
-	for k := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			keys = append(keys, k)
-		}
-	}
-	return keys
-}
-
-// Len returns the number of items in the cache.
-func (c *LFUCache[K, V]) Len(checkExpired bool) int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if !checkExpired {
-		return len(c.items)
-	}
-	var length int
-	now := time.Now()
-	for k := range c.items {
-		if c.has(k, &now) {
-			length++
+// Victim returns a key with the lowest frequency.
+func (p *lfuPolicy[K]) Victim(incoming K) (K, bool) {
+	for entry := p.freqList.Front(); entry != nil; entry = entry.Next() {
+		for key := range entry.Value.(*freqEntry[K]).items {
+			return key, true
 		}
 	}
-	return length
+	return incoming, false
 }
 
-// Completely clear the cache
-func (c *LFUCache[K, V]) Purge() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.purgeVisitorFunc != nil {
-		for key, item := range c.items {
-			c.purgeVisitorFunc(key, item.value)
-		}
-	}
-
-	c.init()
-}
-
-// IsExpired returns boolean value whether this item is expired or not.
-func (it *lfuItem[K, V]) IsExpired(now *time.Time) bool {
-	if it.expiration == nil {
-		return false
-	}
-	if now == nil {
-		t := it.clock.Now()
-		now = &t
-	}
-	return it.expiration.Before(*now)
+func (p *lfuPolicy[K]) Reset() {
+	p.freqList = list.New()
+	p.items = make(map[K]*list.Element, p.size)
+	p.freqList.PushFront(&freqEntry[K]{
+		freq:  0,
+		items: make(map[K]struct{}),
+	})
 }
 
-func isRemovableFreqEntry[K comparable, V any](entry *freqEntry[K, V]) bool {
+func isRemovableFreqEntry[K comparable](entry *freqEntry[K]) bool {
 	return entry.freq != 0 && len(entry.items) == 0
 }
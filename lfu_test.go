@@ -1,6 +1,7 @@
 package gcache
 
 import (
+	"container/list"
 	"fmt"
 	"testing"
 	"time"
@@ -92,31 +93,31 @@ func TestLFUFreqListOrder(t *testing.T) {
 			gc.Get(i)
 		}
 	}
-	if l := gc.(*LFUCache[int, int]).freqList.Len(); l != 6 {
+	if l := lfuFreqList(gc).Len(); l != 6 {
 		t.Fatalf("%v != 6", l)
 	}
 	var i uint
-	for e := gc.(*LFUCache[int, int]).freqList.Front(); e != nil; e = e.Next() {
-		if e.Value.(*freqEntry[int, int]).freq != i {
-			t.Fatalf("%v != %v", e.Value.(*freqEntry[int, int]).freq, i)
+	for e := lfuFreqList(gc).Front(); e != nil; e = e.Next() {
+		if e.Value.(*freqEntry[int]).freq != i {
+			t.Fatalf("%v != %v", e.Value.(*freqEntry[int]).freq, i)
 		}
 		i++
 	}
 	gc.Remove(1)
 
-	if l := gc.(*LFUCache[int, int]).freqList.Len(); l != 5 {
+	if l := lfuFreqList(gc).Len(); l != 5 {
 		t.Fatalf("%v != 5", l)
 	}
 	gc.Set(1, 1)
-	if l := gc.(*LFUCache[int, int]).freqList.Len(); l != 5 {
+	if l := lfuFreqList(gc).Len(); l != 5 {
 		t.Fatalf("%v != 5", l)
 	}
 	gc.Get(1)
-	if l := gc.(*LFUCache[int, int]).freqList.Len(); l != 5 {
+	if l := lfuFreqList(gc).Len(); l != 5 {
 		t.Fatalf("%v != 5", l)
 	}
 	gc.Get(1)
-	if l := gc.(*LFUCache[int, int]).freqList.Len(); l != 6 {
+	if l := lfuFreqList(gc).Len(); l != 6 {
 		t.Fatalf("%v != 6", l)
 	}
 }
@@ -127,7 +128,7 @@ func TestLFUFreqListLength(t *testing.T) {
 
 	{
 		gc := buildTestCache[string, string](t, TYPE_LFU, 5)
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 1 {
+		if l := lfuFreqList(gc).Len(); l != 1 {
 			t.Fatalf("%v != 1", l)
 		}
 	}
@@ -137,7 +138,7 @@ func TestLFUFreqListLength(t *testing.T) {
 		for i := 0; i < 5; i++ {
 			gc.Get(k0)
 		}
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 2 {
+		if l := lfuFreqList(gc).Len(); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
@@ -150,7 +151,7 @@ func TestLFUFreqListLength(t *testing.T) {
 			gc.Get(k0)
 			gc.Get(k1)
 		}
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 2 {
+		if l := lfuFreqList(gc).Len(); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
@@ -162,13 +163,13 @@ func TestLFUFreqListLength(t *testing.T) {
 		for i := 0; i < 5; i++ {
 			gc.Get(k0)
 		}
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 2 {
+		if l := lfuFreqList(gc).Len(); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 		for i := 0; i < 5; i++ {
 			gc.Get(k1)
 		}
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 2 {
+		if l := lfuFreqList(gc).Len(); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
@@ -177,20 +178,39 @@ func TestLFUFreqListLength(t *testing.T) {
 		gc := buildTestCache[string, string](t, TYPE_LFU, 5)
 		gc.Set(k0, v0)
 		gc.Get(k0)
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 2 {
+		if l := lfuFreqList(gc).Len(); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 		gc.Remove(k0)
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 1 {
+		if l := lfuFreqList(gc).Len(); l != 1 {
 			t.Fatalf("%v != 1", l)
 		}
 		gc.Set(k0, v0)
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 1 {
+		if l := lfuFreqList(gc).Len(); l != 1 {
 			t.Fatalf("%v != 1", l)
 		}
 		gc.Get(k0)
-		if l := gc.(*LFUCache[string, string]).freqList.Len(); l != 2 {
+		if l := lfuFreqList(gc).Len(); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
 }
+
+func lfuFreqList[K comparable, V any](gc Cache[K, V]) *list.List {
+	return gc.(*LFUCache[K, V]).policy.(*lfuPolicy[K]).freqList
+}
+
+func TestLFUUpdateNotCounted(t *testing.T) {
+	gc := buildTestCache[int, int](t, TYPE_LFU, 2)
+	gc.Set(1, 1)
+	gc.Set(2, 2)
+	gc.Get(2)
+	// updates do not raise the frequency of a key, only reads do
+	for i := 0; i < 3; i++ {
+		gc.Set(1, i)
+	}
+	gc.Set(3, 3)
+	if gc.Has(1) || !gc.Has(2) {
+		t.Fatal("the key which has only been updated should have been evicted")
+	}
+}
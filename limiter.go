@@ -0,0 +1,70 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTooManyLoads is returned instead of calling the loader when the number
+// of concurrent loads set with MaxConcurrentLoads is reached and excess loads
+// are rejected.
+var ErrTooManyLoads = errors.New("gcache: too many concurrent loads")
+
+// loadLimiter limits the number of concurrent loader calls.
+type loadLimiter struct {
+	n     int
+	queue bool
+	sem   chan struct{}
+}
+
+func newLoadLimiter(n int, queue bool) *loadLimiter {
+	return &loadLimiter{n: n, queue: queue, sem: make(chan struct{}, n)}
+}
+
+// instance returns a limiter with the configuration of l. A limiter built by
+// the CacheBuilder only holds the configuration and gets a new instance for
+// every cache, while an instance is returned as it is.
+func (l *loadLimiter) instance() *loadLimiter {
+	if l == nil || l.sem != nil {
+		return l
+	}
+	return newLoadLimiter(l.n, l.queue)
+}
+
+// acquire takes a slot for a loader call, which must be given back with
+// release. If all slots are taken it waits for one until ctx is done, or
+// returns ErrTooManyLoads if it does not queue. A nil limiter never blocks.
+func (l *loadLimiter) acquire(ctx context.Context) error {
+	if l.tryAcquire() {
+		return nil
+	}
+	if !l.queue {
+		return ErrTooManyLoads
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tryAcquire takes a slot if one is free, without waiting. A nil limiter
+// always has a free slot.
+func (l *loadLimiter) tryAcquire() bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *loadLimiter) release() {
+	if l != nil {
+		<-l.sem
+	}
+}
@@ -0,0 +1,38 @@
+package gcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoadLimiter(t *testing.T) {
+	l := newLoadLimiter(2, false)
+	for i := 0; i < 2; i++ {
+		if err := l.acquire(context.Background()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := l.acquire(context.Background()); err != ErrTooManyLoads {
+		t.Fatalf("%v != %v", err, ErrTooManyLoads)
+	}
+	l.release()
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	l = newLoadLimiter(1, true)
+	l.acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("%v != %v", err, context.DeadlineExceeded)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.release()
+	}()
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
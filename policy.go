@@ -0,0 +1,999 @@
+package gcache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// EvictionPolicy decides which items are evicted from a cache once it is
+// full. The cache calls the hooks while holding its write lock, so
+// implementations need no synchronization of their own, unless they implement
+// ConcurrentAccessPolicy.
+type EvictionPolicy[K comparable] interface {
+	// OnInsert is called after key has been added to the cache.
+	OnInsert(key K)
+	// OnAccess is called when the value of key has been read, or updated
+	// unless the policy implements UpdatePolicy.
+	OnAccess(key K)
+	// OnRemove is called after key has left the cache, whether it has been
+	// evicted, has expired or has been removed explicitly.
+	OnRemove(key K)
+	// Victim returns the key which should be evicted to make room for the
+	// incoming key. The incoming key may already be in the cache when its
+	// entry grows. When the cache is shrunk by Resize, nothing is inserted and
+	// incoming is an arbitrary key of the cache, for which the policy should
+	// return its regular victim. The returned key must be in the cache.
+	// Returning false means that nothing can be evicted.
+	Victim(incoming K) (K, bool)
+	// Reset is called when the cache is purged and must forget all keys.
+	Reset()
+}
+
+// ConcurrentAccessPolicy is implemented by eviction policies whose OnAccess is
+// safe to call concurrently with other OnAccess calls. Such caches serve hits
+// under the read lock instead of the write lock.
+type ConcurrentAccessPolicy[K comparable] interface {
+	EvictionPolicy[K]
+	ConcurrentAccess() bool
+}
+
+// UpdatePolicy is implemented by eviction policies which treat an update of
+// the value of a key differently from a read. OnUpdate is called instead of
+// OnAccess when the value of key has been updated.
+type UpdatePolicy[K comparable] interface {
+	EvictionPolicy[K]
+	OnUpdate(key K)
+}
+
+// ResizablePolicy is implemented by eviction policies whose internal
+// structures depend on the size of the cache. Resize is called after the
+// cache has evicted the items exceeding the new size. A cache with a
+// MaximumWeight also calls it with the number of items it holds when it
+// grows beyond the size of the policy, and when it is full.
+type ResizablePolicy[K comparable] interface {
+	EvictionPolicy[K]
+	Resize(size int)
+}
+
+// PolicyFactory creates an eviction policy for a cache dimensioned for size
+// items.
+type PolicyFactory[K comparable] func(size int) EvictionPolicy[K]
+
+// evictType identifies a registered eviction policy by its name and the type
+// of the keys it is registered for.
+type evictType struct {
+	name string
+	key  reflect.Type
+}
+
+var (
+	evictTypesMu sync.RWMutex
+	evictTypes   = make(map[evictType]any)
+)
+
+// RegisterEvictType makes an eviction policy available through
+// CacheBuilder.EvictType for caches with keys of type K. The same name can be
+// registered once for every key type. It panics if tp is empty, names a
+// built-in type or has already been registered for K.
+func RegisterEvictType[K comparable](tp string, factory PolicyFactory[K]) {
+	if tp == "" || factory == nil {
+		panic("gcache: RegisterEvictType with empty type or nil factory")
+	}
+	switch tp {
+	case TYPE_SIMPLE, TYPE_LRU, TYPE_LFU, TYPE_ARC, TYPE_TINYLFU, TYPE_S3FIFO, TYPE_SIEVE:
+		panic("gcache: RegisterEvictType of built-in type " + tp)
+	}
+	evictTypesMu.Lock()
+	defer evictTypesMu.Unlock()
+	key := evictType{tp, reflect.TypeFor[K]()}
+	if _, ok := evictTypes[key]; ok {
+		panic(fmt.Sprintf("gcache: RegisterEvictType called twice for type %s and keys of type %v", tp, key.key))
+	}
+	evictTypes[key] = factory
+}
+
+func lookupEvictType[K comparable](tp string) PolicyFactory[K] {
+	evictTypesMu.RLock()
+	defer evictTypesMu.RUnlock()
+	key := evictType{tp, reflect.TypeFor[K]()}
+	if f, ok := evictTypes[key]; ok {
+		return f.(PolicyFactory[K])
+	}
+	for registered := range evictTypes {
+		if registered.name == tp {
+			panic(fmt.Sprintf("gcache: type %s is not registered for keys of type %v", tp, key.key))
+		}
+	}
+	panic("gcache: Unknown type " + tp)
+}
+
+// policyCache implements Cache on top of an EvictionPolicy. All built-in
+// cache types are policyCaches with different policies.
+type policyCache[K comparable, V any] struct {
+	baseCache[K, V]
+	items  map[K]*cacheItem[K, V]
+	policy EvictionPolicy[K]
+	weight int64
+	wheel  *timerWheel[K, V]
+	// stale is the number of expired items kept for StaleIfError
+	stale int
+	// negative holds the loader errors cached for NegativeTTL
+	negative Cache[K, error]
+	batcher  *batcher[K, V]
+	// tags indexes the keys of the items by their tags
+	tags map[string]map[K]struct{}
+	// generation counts the writes of items
+	generation uint64
+	// policySize is the size the policy is dimensioned for
+	policySize int
+
+	concurrentAccess bool
+	janitor          *janitor
+}
+
+var _ Cache[int, int] = (*policyCache[int, int])(nil)
+
+func newPolicyCache[K comparable, V any](cb *CacheBuilder[K, V], policy EvictionPolicy[K]) *policyCache[K, V] {
+	c := &policyCache[K, V]{}
+	buildPolicyCache(c, cb, policy)
+	return c
+}
+
+func buildPolicyCache[K comparable, V any](c *policyCache[K, V], cb *CacheBuilder[K, V], policy EvictionPolicy[K]) {
+	buildCache(&c.baseCache, cb)
+	c.policy = policy
+	c.policySize = cb.size
+	// a hit moves the expiration time, which needs the write lock
+	if p, ok := policy.(ConcurrentAccessPolicy[K]); ok && c.accessExpiration == nil && c.expiry == nil {
+		c.concurrentAccess = p.ConcurrentAccess()
+	}
+	c.init()
+	c.loadGroup.cache = c
+	c.loadGroup.cancelAbandoned = cb.cancelAbandoned
+	c.loadGroup.detach = cb.detachLoader
+	if cb.negativeTTL > 0 {
+		c.negative = newNegativeCache(cb)
+	}
+	if cb.batchWindow > 0 {
+		c.batcher = newBatcher(cb.batchWindow, cb.maxBatchSize, c.loadMany)
+	}
+	if cb.cleanupInterval > 0 {
+		c.janitor = startJanitor(cb.cleanupInterval, c.cleanup)
+	}
+}
+
+func (c *policyCache[K, V]) init() {
+	if c.size <= 0 {
+		c.items = make(map[K]*cacheItem[K, V])
+	} else {
+		c.items = make(map[K]*cacheItem[K, V], c.size+1)
+	}
+	c.weight = 0
+	c.stale = 0
+	c.wheel = newTimerWheel[K, V](c.clock.Now())
+	c.tags = nil
+}
+
+// set stores a new key-value pair with the metadata of ev. A non-nil
+// expiration takes precedence over the expiration configured for the cache,
+// and a non-zero weight over the Weigher.
+func (c *policyCache[K, V]) set(key K, ev entryValue[V]) (*cacheItem[K, V], error) {
+	value, expiration := ev.value, ev.expiration
+	var err error
+	if c.serializeFunc != nil {
+		value, err = c.serializeFunc(key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	weight := int64(1)
+	if ev.weight > 0 {
+		weight = ev.weight
+	} else if c.weigher != nil {
+		weight = c.weigher(key, value)
+	}
+	if c.maximumWeight > 0 && weight > c.maximumEntryWeight {
+		return nil, &EntryTooHeavyError{Weight: weight, MaximumWeight: c.maximumEntryWeight}
+	}
+	c.evict(key, weight)
+
+	if c.negative != nil {
+		c.negative.Remove(key)
+	}
+
+	// Check for existing item
+	now := c.clock.Now()
+	c.generation++
+	item, ok := c.items[key]
+	if ok {
+		item.value = value
+		item.generation = c.generation
+		item.writeTime = now
+		item.loadTime = 0
+		if item.stale {
+			item.stale = false
+			c.stale--
+		}
+		c.weight += weight - item.weight
+		item.weight = weight
+		c.untag(key, item.tags)
+		item.tags = ev.tags
+		item.version = ev.version
+		if p, ok := c.policy.(UpdatePolicy[K]); ok {
+			p.OnUpdate(key)
+		} else {
+			c.policy.OnAccess(key)
+		}
+	} else {
+		item = &cacheItem[K, V]{
+			clock:      c.clock,
+			key:        key,
+			value:      value,
+			weight:     weight,
+			writeTime:  now,
+			tags:       ev.tags,
+			version:    ev.version,
+			generation: c.generation,
+		}
+		c.items[key] = item
+		c.weight += weight
+		if c.maximumWeight > 0 && len(c.items) > c.policySize {
+			c.resizePolicy(len(c.items))
+		}
+		c.policy.OnInsert(key)
+	}
+	c.tag(key, ev.tags)
+
+	switch {
+	case expiration != nil:
+		c.expireAfter(item, now, *expiration)
+	case c.expiry != nil && ok:
+		c.expireAfter(item, now, c.expiry.ExpireAfterUpdate(key, value, item.remaining(now)))
+	case c.expiry != nil:
+		c.expireAfter(item, now, c.expiry.ExpireAfterCreate(key, value))
+	case c.expiration != nil:
+		c.expireAfter(item, now, *c.expiration)
+	case c.accessExpiration != nil:
+		c.touch(item, now)
+	}
+
+	if c.addedFunc != nil {
+		c.addedFunc(key, value)
+	}
+
+	return item, nil
+}
+
+// expireAfter sets the expiration time of item after it has been written at
+// now.
+func (c *policyCache[K, V]) expireAfter(item *cacheItem[K, V], now time.Time, expiration time.Duration) {
+	item.writeExpiration = nil
+	if expiration != NoExpiration {
+		t := now.Add(expiration)
+		item.writeExpiration = &t
+	}
+	c.touch(item, now)
+}
+
+// touch updates the expiration time of item after it has been accessed at
+// now and schedules it in the timer wheel. The expiration after access can
+// only bring the expiration after write forward.
+func (c *policyCache[K, V]) touch(item *cacheItem[K, V], now time.Time) {
+	item.expiration = item.writeExpiration
+	if c.accessExpiration != nil {
+		t := now.Add(*c.accessExpiration)
+		if item.expiration == nil || t.Before(*item.expiration) {
+			item.expiration = &t
+		}
+	}
+	if item.expiration != nil {
+		c.wheel.schedule(item, *item.expiration)
+	} else {
+		c.wheel.unschedule(item)
+	}
+}
+
+// expire removes all expired items found by advancing the timer wheel. With
+// StaleIfError, expired items are marked stale and only removed once their
+// grace period is over. A positive limit caps the number of items visited,
+// and expire returns false if there are more left.
+func (c *policyCache[K, V]) expire(now time.Time, limit int) bool {
+	return c.wheel.advance(now, limit, func(item *cacheItem[K, V]) {
+		if c.staleIfError > 0 && !item.stale {
+			item.stale = true
+			c.stale++
+			c.wheel.schedule(item, item.expiration.Add(c.staleIfError))
+			return
+		}
+		c.remove(item.key)
+	})
+}
+
+// removeExpired removes the expired item of key, unless it is within the
+// grace period of StaleIfError.
+func (c *policyCache[K, V]) removeExpired(key K, now time.Time) {
+	if item, ok := c.items[key]; ok && !item.inGrace(c.staleIfError, now) {
+		c.remove(key)
+	}
+}
+
+// evict removes the victims chosen by the policy until key fits into the
+// cache with the given weight. If key is chosen itself, its current entry is
+// evicted and key is inserted again. Expired items are removed before the
+// policy is asked for a victim.
+func (c *policyCache[K, V]) evict(key K, weight int64) {
+	if !c.exceeds(key, weight) {
+		return
+	}
+	c.expire(c.clock.Now(), 0)
+	if c.maximumWeight > 0 {
+		// a full weighted cache holds as many items as fit into its weight,
+		// which is what the segments of the policy are sized by
+		c.resizePolicy(max(len(c.items), 1))
+	}
+	for c.exceeds(key, weight) {
+		victim, ok := c.policy.Victim(key)
+		if !ok || !c.remove(victim) {
+			return
+		}
+	}
+}
+
+// exceeds reports whether setting key with the given weight would exceed the
+// capacity of the cache. A size <= 0 means the cache is unbounded.
+func (c *policyCache[K, V]) exceeds(key K, weight int64) bool {
+	item, ok := c.items[key]
+	if c.maximumWeight > 0 {
+		if ok {
+			weight -= item.weight
+		}
+		return c.weight+weight > c.maximumWeight
+	}
+	return !ok && c.size > 0 && len(c.items) >= c.size
+}
+
+// Resize changes the number of items the cache can hold, or its maximum
+// weight when the capacity is measured by weight. Shrinking evicts items in
+// the order chosen by the eviction policy.
+func (c *policyCache[K, V]) Resize(newSize int) {
+	c.resize(newSize, int64(newSize))
+}
+
+// resize implements Resize. maximumEntryWeight is the maximum weight of a
+// single entry, which for the shards of a sharded cache is the new maximum
+// weight of the whole cache.
+func (c *policyCache[K, V]) resize(newSize int, maximumEntryWeight int64) {
+	if _, unbounded := c.policy.(*simplePolicy[K]); newSize <= 0 && (!unbounded || c.maximumWeight > 0) {
+		panic("gcache: Cache size <= 0")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maximumWeight > 0 {
+		c.maximumWeight = int64(newSize)
+		c.maximumEntryWeight = maximumEntryWeight
+	} else {
+		c.size = newSize
+	}
+	for c.overCapacity() {
+		// any key in the cache lets the policy pick its regular victim
+		var key K
+		for key = range c.items {
+			break
+		}
+		victim, ok := c.policy.Victim(key)
+		if !ok || !c.remove(victim) {
+			break
+		}
+	}
+	if c.maximumWeight > 0 {
+		c.resizePolicy(max(len(c.items), 1))
+	} else {
+		c.resizePolicy(newSize)
+	}
+}
+
+// overCapacity reports whether the cache holds more than its capacity, as
+// after it has been shrunk.
+func (c *policyCache[K, V]) overCapacity() bool {
+	if c.maximumWeight > 0 {
+		return c.weight > c.maximumWeight
+	}
+	return c.size > 0 && len(c.items) > c.size
+}
+
+// resizePolicy dimensions a ResizablePolicy for size items.
+func (c *policyCache[K, V]) resizePolicy(size int) {
+	if p, ok := c.policy.(ResizablePolicy[K]); ok && size != c.policySize {
+		c.policySize = size
+		p.Resize(size)
+	}
+}
+
+// cleanup removes expired items. To hold the lock only briefly, it visits at
+// most cleanupBatchSize items of the timer wheel per lock acquisition.
+func (c *policyCache[K, V]) cleanup() {
+	now := c.clock.Now()
+	for done := false; !done; {
+		c.mu.Lock()
+		done = c.expire(now, cleanupBatchSize)
+		c.mu.Unlock()
+	}
+}
+
+// newNegativeCache returns the cache of loader errors for NegativeTTL, which
+// holds as many errors as cb holds items.
+func newNegativeCache[K comparable, V any](cb *CacheBuilder[K, V]) Cache[K, error] {
+	tp := TYPE_LRU
+	if cb.size <= 0 {
+		tp = TYPE_SIMPLE
+	}
+	return New[K, error](cb.size).
+		EvictType(tp).
+		Clock(cb.clock).
+		Expiration(cb.negativeTTL).
+		Build()
+}
+
+// Close stops the background cleanup of expired items.
+func (c *policyCache[K, V]) Close() {
+	if c.janitor != nil {
+		c.janitor.Stop()
+	}
+}
+
+// Set a new key-value pair
+func (c *policyCache[K, V]) Set(key K, value V) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.set(key, entryValue[V]{value: value})
+	return err
+}
+
+// SetWithExpire Set a new key-value pair with an expiration time
+func (c *policyCache[K, V]) SetWithExpire(key K, value V, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.set(key, entryValue[V]{value: value, expiration: &expiration})
+	return err
+}
+
+// Get a value from cache pool using key if it exists. If it does not exists key
+// and has LoaderFunc, generate a value using `LoaderFunc` method returns value.
+func (c *policyCache[K, V]) Get(key K) (V, error) {
+	return c.GetWithContext(context.Background(), key)
+}
+
+// GetIFPresent gets a value from cache pool using key if it exists. If it does
+// not exists key, returns KeyNotFoundError. And send a request which refresh
+// value for specified key if cache object has LoaderFunc.
+func (c *policyCache[K, V]) GetIFPresent(key K) (V, error) {
+	return c.GetIFPresentWithContext(context.Background(), key)
+}
+
+func (c *policyCache[K, V]) GetWithContext(ctx context.Context, key K) (V, error) {
+	v, err := c.getContext(ctx, key, false)
+	if errors.Is(err, KeyNotFoundError) {
+		return c.getWithLoader(ctx, key, true)
+	}
+	return v, err
+}
+
+func (c *policyCache[K, V]) GetIFPresentWithContext(ctx context.Context, key K) (V, error) {
+	v, err := c.getContext(ctx, key, false)
+	if errors.Is(err, KeyNotFoundError) {
+		return c.getWithLoader(ctx, key, false)
+	}
+	return v, err
+}
+
+func (c *policyCache[K, V]) get(key K, onLoad bool) (V, error) {
+	return c.getContext(context.Background(), key, onLoad)
+}
+
+// getContext returns the value of key. A hit on an item which is due for
+// refresh starts a reload in the background, which keeps the values of ctx.
+func (c *policyCache[K, V]) getContext(ctx context.Context, key K, onLoad bool) (v V, _ error) {
+	v, err := c.getValue(ctx, key, onLoad)
+	if err != nil {
+		return v, err
+	}
+	if c.deserializeFunc != nil {
+		return c.deserializeFunc(key, v)
+	}
+	return v, nil
+}
+
+func (c *policyCache[K, V]) getValue(ctx context.Context, key K, onLoad bool) (v V, _ error) {
+	found, expired := false, false
+	var refresh uint64
+	if c.concurrentAccess {
+		c.mu.RLock()
+		v, found, expired, refresh = c.lookup(key, onLoad)
+		c.mu.RUnlock()
+	}
+	if !c.concurrentAccess || expired {
+		c.mu.Lock()
+		v, found, expired, refresh = c.lookup(key, onLoad)
+		if expired {
+			c.removeExpired(key, c.clock.Now())
+		}
+		c.mu.Unlock()
+	}
+	if !found {
+		if !onLoad {
+			c.stats.IncrMissCount()
+		}
+		return v, KeyNotFoundError
+	}
+	if !onLoad {
+		c.stats.IncrHitCount()
+	}
+	if refresh != 0 {
+		c.refresh(ctx, key, refresh)
+	}
+	return v, nil
+}
+
+// lookup returns the value of key and reports the access to the policy. If
+// the item is due for refresh, refresh is its write generation. It must be
+// called with at least the read lock held.
+func (c *policyCache[K, V]) lookup(key K, onLoad bool) (v V, found, expired bool, refresh uint64) {
+	item, ok := c.items[key]
+	if !ok {
+		return v, false, false, 0
+	}
+	if item.IsExpired(nil) {
+		return v, false, true, 0
+	}
+	if !onLoad {
+		c.policy.OnAccess(key)
+		if c.expiry != nil {
+			now := c.clock.Now()
+			c.expireAfter(item, now, c.expiry.ExpireAfterRead(key, item.value, item.remaining(now)))
+		} else if c.accessExpiration != nil {
+			c.touch(item, c.clock.Now())
+		}
+		if c.loadFunc != nil && c.dueForRefresh(item, c.clock.Now()) {
+			refresh = item.generation
+		}
+	}
+	return item.value, true, false, refresh
+}
+
+func (c *policyCache[K, V]) getWithLoader(ctx context.Context, key K, isWait bool) (v V, _ error) {
+	if c.loadFunc == nil && c.batcher == nil {
+		return v, KeyNotFoundError
+	}
+	// a cached loader error falls back to a stale value like a failed load
+	err, negative := c.negativeErr(key)
+	var value V
+	switch {
+	case negative:
+	case c.batcher != nil:
+		value, _, err = c.loadGroup.Do(ctx, key, func(ctx context.Context) (V, error) {
+			return c.batcher.Load(ctx, key)
+		}, isWait)
+	default:
+		value, _, err = c.load(ctx, key, func(ev entryValue[V], loadTime time.Duration, e error) (ret V, _ error) {
+			if e != nil {
+				if c.negative != nil && c.negativeCacheable(e) {
+					c.negative.Set(key, e)
+				}
+				return ret, e
+			}
+			return c.setLoaded(key, ev, loadTime, 0)
+		}, isWait)
+	}
+	if err != nil {
+		if stale, ok := c.staleValue(key); ok && isWait {
+			return stale, &StaleValueError{Err: err}
+		}
+		return v, err
+	}
+	return value, nil
+}
+
+// negativeErr returns the loader error cached for key by NegativeTTL.
+func (c *policyCache[K, V]) negativeErr(key K) (error, bool) {
+	if c.negative == nil {
+		return nil, false
+	}
+	err, e := c.negative.GetIFPresent(key)
+	if e != nil {
+		return nil, false
+	}
+	c.stats.IncrNegativeHitCount()
+	return err, true
+}
+
+// GetMany returns the values of keys. Keys present in the cache are served
+// from the cache, and all others are loaded with a single call of the
+// BulkLoaderFunc. Keys already being loaded are waited on instead. Without a
+// BulkLoaderFunc, the keys are loaded one by one with the LoaderFunc. Keys
+// with an error cached by NegativeTTL are missing from the result.
+func (c *policyCache[K, V]) GetMany(ctx context.Context, keys []K) (map[K]V, error) {
+	values := make(map[K]V, len(keys))
+	var misses []K
+	for _, key := range keys {
+		v, err := c.getContext(ctx, key, false)
+		if err == nil {
+			values[key] = v
+			continue
+		}
+		if !errors.Is(err, KeyNotFoundError) {
+			return nil, err
+		}
+		// keys with a cached loader error are treated as not found
+		if _, ok := c.negativeErr(key); ok {
+			continue
+		}
+		misses = append(misses, key)
+	}
+	if len(misses) == 0 {
+		return values, nil
+	}
+
+	if c.bulkLoaderFunc == nil {
+		for _, key := range misses {
+			v, err := c.getWithLoader(ctx, key, true)
+			if err == nil {
+				values[key] = v
+			} else if !errors.Is(err, KeyNotFoundError) {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	loaded, err := c.loadGroup.doMany(ctx, misses, c.loadMany)
+	if err != nil {
+		return nil, err
+	}
+	for key, v := range loaded {
+		values[key] = v
+	}
+	return values, nil
+}
+
+// loadMany loads keys with the BulkLoaderFunc and stores the values. Keys
+// which are not found are cached as not found if NegativeTTL is set.
+func (c *policyCache[K, V]) loadMany(ctx context.Context, keys []K) (values map[K]V, err error) {
+	if err := c.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.limiter.release()
+	generation, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	recorded := false
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("loader panics: %v", r)
+			// a panic storing the values comes after the result was recorded
+			if !recorded {
+				c.breaker.record(generation, err)
+			}
+		}
+	}()
+	ctx, cancel := c.loaderContext(ctx)
+	defer cancel()
+	start := c.clock.Now()
+	err = c.retry.do(ctx, func() (err error) {
+		values, err = c.bulkLoaderFunc(ctx, keys)
+		return err
+	}, func() { c.IncrRetryCount() })
+	c.breaker.record(generation, err)
+	recorded = true
+	if err != nil {
+		return nil, err
+	}
+	loadTime := c.clock.Now().Sub(start)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		v, ok := values[key]
+		if !ok {
+			if c.negative != nil && c.negativeCacheable(KeyNotFoundError) {
+				c.negative.Set(key, KeyNotFoundError)
+			}
+			continue
+		}
+		if item, err := c.set(key, entryValue[V]{value: v}); err == nil {
+			item.loadTime = loadTime
+		}
+	}
+	return values, nil
+}
+
+// staleValue returns the value of the expired item of key if it is within
+// the grace period of StaleIfError.
+func (c *policyCache[K, V]) staleValue(key K) (v V, ok bool) {
+	if c.staleIfError <= 0 {
+		return v, false
+	}
+	c.mu.RLock()
+	now := c.clock.Now()
+	if item, found := c.items[key]; found && item.IsExpired(&now) && item.inGrace(c.staleIfError, now) {
+		v, ok = item.value, true
+	}
+	c.mu.RUnlock()
+	if ok && c.deserializeFunc != nil {
+		var err error
+		if v, err = c.deserializeFunc(key, v); err != nil {
+			return v, false
+		}
+	}
+	return v, ok
+}
+
+// refresh reloads key, whose item has the write generation generation, in the
+// background. The value of key is kept until the reload succeeds, and it is
+// kept if the reload fails. The reloaded value is dropped if key has been
+// written or removed in the meantime.
+func (c *policyCache[K, V]) refresh(ctx context.Context, key K, generation uint64) {
+	c.reload(context.WithoutCancel(ctx), key, func(ev entryValue[V], loadTime time.Duration, e error) (ret V, _ error) {
+		if e != nil {
+			return ret, e
+		}
+		return c.setLoaded(key, ev, loadTime, generation)
+	})
+}
+
+// dueForRefresh reports whether item should be reloaded in the background at
+// now, either because it is older than RefreshAfterWrite, or because EarlyRefresh
+// decided to recompute it before it expires.
+func (c *policyCache[K, V]) dueForRefresh(item *cacheItem[K, V], now time.Time) bool {
+	if c.refreshAfterWrite != nil && now.Sub(item.writeTime) >= *c.refreshAfterWrite {
+		return true
+	}
+	if c.earlyRefresh <= 0 || item.expiration == nil || item.loadTime <= 0 {
+		return false
+	}
+	// XFetch: recompute when now - loadTime * beta * ln(rand) reaches the
+	// expiration time, which gets more likely the closer the expiration time
+	// is and the longer the load takes
+	gap := -float64(item.loadTime) * c.earlyRefresh * math.Log(1-rand.Float64())
+	return !now.Add(time.Duration(gap)).Before(*item.expiration)
+}
+
+// setLoaded stores a value returned by the loader after loadTime, unless the
+// loader asked not to cache it or a newer version of it is cached. A non-zero
+// generation is the write generation of the item the value replaces; the
+// value is not stored if the item has been written or removed since.
+func (c *policyCache[K, V]) setLoaded(key K, ev entryValue[V], loadTime time.Duration, generation uint64) (ret V, _ error) {
+	if ev.noCache {
+		return ev.value, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	item, ok := c.items[key]
+	if generation != 0 && (!ok || item.generation != generation) {
+		return ev.value, nil
+	}
+	if ok && item.version > ev.version && !item.IsExpired(&now) {
+		return ev.value, nil
+	}
+	item, err := c.set(key, ev)
+	if err != nil {
+		return ret, err
+	}
+	item.loadTime = loadTime
+	return ev.value, nil
+}
+
+// Has checks if key exists in cache
+func (c *policyCache[K, V]) Has(key K) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.clock.Now()
+	return c.has(key, &now)
+}
+
+func (c *policyCache[K, V]) has(key K, now *time.Time) bool {
+	item, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	return !item.IsExpired(now)
+}
+
+// Remove removes the provided key from the cache.
+func (c *policyCache[K, V]) Remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.negative != nil {
+		c.negative.Remove(key)
+	}
+	return c.remove(key)
+}
+
+func (c *policyCache[K, V]) remove(key K) bool {
+	item, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	delete(c.items, key)
+	c.weight -= item.weight
+	if item.stale {
+		c.stale--
+	}
+	c.wheel.unschedule(item)
+	c.untag(key, item.tags)
+	c.policy.OnRemove(key)
+	if c.evictedFunc != nil {
+		c.evictedFunc(key, item.value)
+	}
+	return true
+}
+
+// RemoveByTag removes the items tagged with tag by a ResultLoaderFunc and
+// returns their number.
+func (c *policyCache[K, V]) RemoveByTag(tag string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key := range c.tags[tag] {
+		if c.remove(key) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// tag adds key to the index of tags.
+func (c *policyCache[K, V]) tag(key K, tags []string) {
+	for _, tag := range tags {
+		if c.tags == nil {
+			c.tags = make(map[string]map[K]struct{})
+		}
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// untag removes key from the index of tags.
+func (c *policyCache[K, V]) untag(key K, tags []string) {
+	for _, tag := range tags {
+		keys := c.tags[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// GetALL returns all key-value pairs in the cache. Checking for expired items
+// removes them first and skips stale items.
+func (c *policyCache[K, V]) GetALL(checkExpired bool) map[K]V {
+	unlock := c.lockAll(checkExpired)
+	defer unlock()
+	items := make(map[K]V, len(c.items))
+	for k, item := range c.items {
+		if !checkExpired || !item.stale {
+			items[k] = item.value
+		}
+	}
+	return items
+}
+
+// Keys returns a slice of the keys in the cache. Checking for expired items
+// removes them first and skips stale items.
+func (c *policyCache[K, V]) Keys(checkExpired bool) []K {
+	unlock := c.lockAll(checkExpired)
+	defer unlock()
+	keys := make([]K, 0, len(c.items))
+	for k, item := range c.items {
+		if !checkExpired || !item.stale {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache. Checking for expired items
+// removes them first and skips stale items.
+func (c *policyCache[K, V]) Len(checkExpired bool) int {
+	unlock := c.lockAll(checkExpired)
+	defer unlock()
+	if checkExpired {
+		return len(c.items) - c.stale
+	}
+	return len(c.items)
+}
+
+// lockAll locks the cache for reading all items. If checkExpired is set, it
+// takes the write lock and removes the expired items.
+func (c *policyCache[K, V]) lockAll(checkExpired bool) (unlock func()) {
+	if !checkExpired {
+		c.mu.RLock()
+		return c.mu.RUnlock
+	}
+	c.mu.Lock()
+	c.expire(c.clock.Now(), 0)
+	return c.mu.Unlock
+}
+
+// Purge Completely clear the cache
+func (c *policyCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.purgeVisitorFunc != nil {
+		for key, item := range c.items {
+			c.purgeVisitorFunc(key, item.value)
+		}
+	}
+
+	c.init()
+	c.policy.Reset()
+	if c.negative != nil {
+		c.negative.Purge()
+	}
+}
+
+type cacheItem[K comparable, V any] struct {
+	clock      Clock
+	key        K
+	value      V
+	weight     int64
+	writeTime  time.Time
+	loadTime   time.Duration
+	expiration *time.Time
+	// writeExpiration is the expiration time set by the last write, before
+	// the expiration after access is applied
+	writeExpiration *time.Time
+	// stale is set once the item has expired and is kept for StaleIfError
+	stale bool
+	// tags and version are set by a ResultLoaderFunc
+	tags    []string
+	version uint64
+	// generation is the write generation of the item
+	generation uint64
+
+	timerTime int64
+
+	timer       *list.Element
+	timerBucket *list.List
+}
+
+// IsExpired returns boolean value whether this item is expired or not.
+func (it *cacheItem[K, V]) IsExpired(now *time.Time) bool {
+	if it.expiration == nil {
+		return false
+	}
+	if now == nil {
+		t := it.clock.Now()
+		now = &t
+	}
+	return it.expiration.Before(*now)
+}
+
+// inGrace reports whether the item has not expired for longer than grace.
+func (it *cacheItem[K, V]) inGrace(grace time.Duration, now time.Time) bool {
+	return grace > 0 && it.expiration != nil && now.Before(it.expiration.Add(grace))
+}
+
+// remaining returns the time until the item expires, or NoExpiration.
+func (it *cacheItem[K, V]) remaining(now time.Time) time.Duration {
+	if it.expiration == nil {
+		return NoExpiration
+	}
+	return it.expiration.Sub(now)
+}
@@ -0,0 +1,124 @@
+package gcache
+
+import (
+	"testing"
+)
+
+// fifoPolicy evicts keys in insertion order.
+type fifoPolicy[K comparable] struct {
+	keys []K
+}
+
+func (p *fifoPolicy[K]) OnInsert(key K) {
+	p.keys = append(p.keys, key)
+}
+
+func (p *fifoPolicy[K]) OnAccess(key K) {}
+
+func (p *fifoPolicy[K]) OnRemove(key K) {
+	for i, k := range p.keys {
+		if k == key {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *fifoPolicy[K]) Victim(incoming K) (K, bool) {
+	if len(p.keys) == 0 {
+		return incoming, false
+	}
+	return p.keys[0], true
+}
+
+func (p *fifoPolicy[K]) Reset() {
+	p.keys = nil
+}
+
+func init() {
+	RegisterEvictType("test-fifo", func(size int) EvictionPolicy[int] {
+		return &fifoPolicy[int]{}
+	})
+	RegisterEvictType("test-fifo", func(size int) EvictionPolicy[string] {
+		return &fifoPolicy[string]{}
+	})
+}
+
+func TestRegisteredEvictType(t *testing.T) {
+	var evicted []int
+	gc := New[int, int](3).
+		EvictType("test-fifo").
+		EvictedFunc(func(key, value int) {
+			evicted = append(evicted, key)
+		}).
+		Build()
+
+	setItemsByRange(t, gc, 0, 3)
+	gc.Get(0)
+	setItemsByRange(t, gc, 3, 5)
+
+	if len(evicted) != 2 || evicted[0] != 0 || evicted[1] != 1 {
+		t.Fatalf("unexpected evicted keys %v", evicted)
+	}
+	checkItemsByRange(t, gc.Keys(false), gc.GetALL(false), gc.Len(false), 2, 5)
+
+	gc.Purge()
+	if l := gc.Len(false); l != 0 {
+		t.Fatalf("%v != 0", l)
+	}
+	setItemsByRange(t, gc, 0, 4)
+	if gc.Has(0) {
+		t.Fatal("should not have 0")
+	}
+}
+
+func TestRegisteredEvictTypeKeyTypes(t *testing.T) {
+	gc := New[string, int](2).EvictType("test-fifo").Build()
+	gc.Set("a", 1)
+	gc.Get("a")
+	gc.Set("b", 2)
+	gc.Set("c", 3)
+	if gc.Has("a") || !gc.Has("b") || !gc.Has("c") {
+		t.Fatal("keys of type string not evicted in insertion order")
+	}
+}
+
+func TestRegisteredEvictTypeKeyMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	New[float64, int](3).EvictType("test-fifo").Build()
+}
+
+func TestRegisterEvictTypeTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	RegisterEvictType("test-fifo", func(size int) EvictionPolicy[string] {
+		return &fifoPolicy[string]{}
+	})
+}
+
+func TestUnknownEvictType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	New[int, int](3).EvictType("unknown").Build()
+}
+
+func TestRegisterBuiltInEvictType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	RegisterEvictType(TYPE_LRU, func(size int) EvictionPolicy[int] {
+		return &fifoPolicy[int]{}
+	})
+}
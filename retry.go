@@ -0,0 +1,69 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// retryPolicy retries failed loader calls with an exponential backoff.
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	retryable   func(error) bool
+}
+
+// do calls fn until it succeeds, fails with an error which is not retryable
+// or has been called maxAttempts times. onRetry is called before every retry.
+// It gives up early once ctx is done or its deadline would pass during the
+// backoff, returning the last error of fn. A nil policy calls fn once.
+func (p *retryPolicy) do(ctx context.Context, fn func() error, onRetry func()) error {
+	err := fn()
+	if p == nil {
+		return err
+	}
+	for attempt := 1; err != nil && attempt < p.maxAttempts && p.isRetryable(ctx, err); attempt++ {
+		d := p.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+			return err
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		onRetry()
+		err = fn()
+	}
+	return err
+}
+
+// isRetryable reports whether err is worth another attempt. Errors caused by
+// ctx and KeyNotFoundError are never retried, other errors are retried if
+// they pass the retryable function, or always if there is none.
+func (p *retryPolicy) isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.retryable != nil {
+		return p.retryable(err)
+	}
+	return !errors.Is(err, KeyNotFoundError)
+}
+
+// delay returns the backoff before the given retry: backoff doubled for
+// every previous retry, of which a random half is added as jitter.
+func (p *retryPolicy) delay(retry int) time.Duration {
+	d := p.backoff
+	for i := 1; i < retry && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,82 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := &retryPolicy{maxAttempts: 100, backoff: 10 * time.Millisecond}
+	for retry := 1; retry < 6; retry++ {
+		max := p.backoff << (retry - 1)
+		for i := 0; i < 100; i++ {
+			if d := p.delay(retry); d < max/2 || d > max {
+				t.Fatalf("delay %v of retry %v out of [%v, %v]", d, retry, max/2, max)
+			}
+		}
+	}
+	if d := p.delay(99); d <= 0 {
+		t.Fatalf("delay %v overflows", d)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	errTransient := errors.New("transient")
+	errFatal := errors.New("fatal")
+	p := &retryPolicy{
+		maxAttempts: 3,
+		backoff:     time.Millisecond,
+		retryable:   func(err error) bool { return errors.Is(err, errTransient) },
+	}
+
+	for _, tc := range []struct {
+		name     string
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{"success", []error{nil}, 1, nil},
+		{"recovers", []error{errTransient, errTransient, nil}, 3, nil},
+		{"exhausted", []error{errTransient, errTransient, errTransient}, 3, errTransient},
+		{"not retryable", []error{errTransient, errFatal}, 2, errFatal},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts, retries := 0, 0
+			err := p.do(context.Background(), func() error {
+				attempts++
+				return tc.errs[attempts-1]
+			}, func() { retries++ })
+			if err != tc.err {
+				t.Fatalf("%v != %v", err, tc.err)
+			}
+			if attempts != tc.attempts || retries != attempts-1 {
+				t.Fatalf("%v attempts and %v retries, expected %v attempts", attempts, retries, tc.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDeadline(t *testing.T) {
+	p := &retryPolicy{maxAttempts: 10, backoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	attempts := 0
+	start := time.Now()
+	err := p.do(ctx, func() error {
+		attempts++
+		return errors.New("transient")
+	}, func() {})
+	if err == nil || attempts != 1 {
+		t.Fatalf("%v after %v attempts", err, attempts)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("waited past the deadline")
+	}
+
+	var nilPolicy *retryPolicy
+	if err := nilPolicy.do(context.Background(), func() error { return KeyNotFoundError }, nil); err != KeyNotFoundError {
+		t.Fatalf("%v != %v", err, KeyNotFoundError)
+	}
+}
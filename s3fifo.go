@@ -0,0 +1,172 @@
+package gcache
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+const (
+	s3FIFOSmallPercent = 10
+	s3FIFOMaxFreq      = 3
+)
+
+// S3FIFOCache evicts items using three FIFO queues: a small queue receiving
+// new items, a main queue for items which have been accessed while in the
+// small queue and a ghost queue remembering keys recently evicted from the
+// small queue. Hits only bump a counter, so no list is reordered on Get.
+type S3FIFOCache[K comparable, V any] struct {
+	policyCache[K, V]
+}
+
+func newS3FIFOCache[K comparable, V any](cb *CacheBuilder[K, V]) *S3FIFOCache[K, V] {
+	c := &S3FIFOCache[K, V]{}
+	buildPolicyCache(&c.policyCache, cb, newS3FIFOPolicy[K](cb.size))
+	return c
+}
+
+type s3FIFOPolicy[K comparable] struct {
+	size  int
+	items map[K]*s3FIFOEntry[K]
+	small *list.List
+	main  *list.List
+	ghost *arcList[K]
+
+	smallSize int
+}
+
+type s3FIFOEntry[K comparable] struct {
+	key     K
+	freq    int32
+	inMain  bool
+	element *list.Element
+}
+
+func newS3FIFOPolicy[K comparable](size int) *s3FIFOPolicy[K] {
+	p := &s3FIFOPolicy[K]{}
+	p.setSize(size)
+	p.Reset()
+	return p
+}
+
+func (p *s3FIFOPolicy[K]) setSize(size int) {
+	p.size = size
+	p.smallSize = max(1, size*s3FIFOSmallPercent/100)
+}
+
+func (p *s3FIFOPolicy[K]) OnInsert(key K) {
+	entry := &s3FIFOEntry[K]{key: key}
+	if elt := p.ghost.Lookup(key); elt != nil {
+		p.ghost.Remove(key, elt)
+		entry.inMain = true
+		entry.element = p.main.PushFront(entry)
+	} else {
+		entry.element = p.small.PushFront(entry)
+	}
+	p.items[key] = entry
+}
+
+// OnUpdate leaves the frequency of key unchanged, only reads count.
+func (p *s3FIFOPolicy[K]) OnUpdate(key K) {}
+
+// OnAccess records a hit, saturating at s3FIFOMaxFreq. It only reads the
+// items map, so it is safe to call under the read lock.
+func (p *s3FIFOPolicy[K]) OnAccess(key K) {
+	entry, ok := p.items[key]
+	if !ok {
+		return
+	}
+	for {
+		freq := atomic.LoadInt32(&entry.freq)
+		if freq >= s3FIFOMaxFreq || atomic.CompareAndSwapInt32(&entry.freq, freq, freq+1) {
+			return
+		}
+	}
+}
+
+func (p *s3FIFOPolicy[K]) OnRemove(key K) {
+	entry, ok := p.items[key]
+	if !ok {
+		return
+	}
+	if entry.inMain {
+		p.main.Remove(entry.element)
+	} else {
+		p.small.Remove(entry.element)
+	}
+	delete(p.items, key)
+}
+
+// Victim prefers the small queue while it holds more than its share of the
+// cache.
+func (p *s3FIFOPolicy[K]) Victim(incoming K) (K, bool) {
+	if p.small.Len() >= p.smallSize || p.main.Len() == 0 {
+		if key, ok := p.victimSmall(); ok {
+			return key, true
+		}
+	}
+	if key, ok := p.victimMain(); ok {
+		return key, true
+	}
+	return incoming, false
+}
+
+// victimSmall moves items accessed more than once to the main queue and
+// returns the first other item, remembering its key in the ghost queue.
+func (p *s3FIFOPolicy[K]) victimSmall() (K, bool) {
+	for p.small.Len() > 0 {
+		entry := p.small.Back().Value.(*s3FIFOEntry[K])
+		if atomic.LoadInt32(&entry.freq) > 1 {
+			p.small.Remove(entry.element)
+			atomic.StoreInt32(&entry.freq, 0)
+			entry.inMain = true
+			entry.element = p.main.PushFront(entry)
+			if p.main.Len() > p.size-p.smallSize {
+				return p.victimMain()
+			}
+			continue
+		}
+		p.ghost.PushFront(entry.key)
+		if p.ghost.Len() > p.size-p.smallSize {
+			p.ghost.RemoveTail()
+		}
+		return entry.key, true
+	}
+	var key K
+	return key, false
+}
+
+// victimMain reinserts accessed items at the head of the main queue and
+// returns the first item which has not been accessed since its last pass.
+func (p *s3FIFOPolicy[K]) victimMain() (K, bool) {
+	for p.main.Len() > 0 {
+		entry := p.main.Back().Value.(*s3FIFOEntry[K])
+		if freq := atomic.LoadInt32(&entry.freq); freq > 0 {
+			atomic.StoreInt32(&entry.freq, freq-1)
+			p.main.MoveToFront(entry.element)
+			continue
+		}
+		return entry.key, true
+	}
+	var key K
+	return key, false
+}
+
+// Resize adapts the share of the small queue and trims the ghost queue.
+func (p *s3FIFOPolicy[K]) Resize(size int) {
+	p.setSize(size)
+	for p.ghost.Len() > max(0, p.size-p.smallSize) {
+		p.ghost.RemoveTail()
+	}
+}
+
+func (p *s3FIFOPolicy[K]) Reset() {
+	p.items = make(map[K]*s3FIFOEntry[K], p.size+1)
+	p.small = list.New()
+	p.main = list.New()
+	p.ghost = newARCList[K]()
+}
+
+// ConcurrentAccess reports that OnAccess only updates an atomic counter.
+func (p *s3FIFOPolicy[K]) ConcurrentAccess() bool {
+	return true
+}
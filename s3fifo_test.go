@@ -0,0 +1,181 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestS3FIFOGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache[string, string](t, TYPE_S3FIFO, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingS3FIFOGet(t *testing.T) {
+	size := 1000
+	gc := buildTestLoadingCache(t, TYPE_S3FIFO, size, loader)
+	testGetCache(t, gc, size)
+}
+
+func TestS3FIFOLength(t *testing.T) {
+	gc := buildTestLoadingCache(t, TYPE_S3FIFO, 1000, loader)
+	gc.Get("test1")
+	gc.Get("test2")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", length, expectedLength)
+	}
+}
+
+func TestS3FIFOEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := 11
+	gc := buildTestLoadingCache(t, TYPE_S3FIFO, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestS3FIFOGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_S3FIFO)
+}
+
+func TestS3FIFOHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration[string, string](t, TYPE_S3FIFO, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestS3FIFOEvictOneHitWonders(t *testing.T) {
+	size := 10
+	gc := buildTestCache[int, int](t, TYPE_S3FIFO, size)
+
+	for i := 0; i < size; i++ {
+		gc.Set(i, i)
+	}
+	// keys 0 and 1 are accessed more than once while in the small queue
+	for i := 0; i < 2; i++ {
+		gc.Get(0)
+		gc.Get(1)
+	}
+	for i := size; i < 3*size; i++ {
+		gc.Set(i, i)
+	}
+
+	if !gc.Has(0) || !gc.Has(1) {
+		t.Fatal("frequently accessed keys should have been moved to the main queue")
+	}
+	if l := gc.Len(false); l != size {
+		t.Fatalf("%v != %v", l, size)
+	}
+}
+
+func TestS3FIFOGhostHit(t *testing.T) {
+	size := 10
+	gc := buildTestCache[int, int](t, TYPE_S3FIFO, size)
+	p := gc.(*S3FIFOCache[int, int]).policy.(*s3FIFOPolicy[int])
+
+	for i := 0; i < size+1; i++ {
+		gc.Set(i, i)
+	}
+	if gc.Has(0) {
+		t.Fatal("should not have 0")
+	}
+	if !p.ghost.Has(0) {
+		t.Fatal("0 should be remembered by the ghost queue")
+	}
+
+	gc.Set(0, 0)
+	if !p.items[0].inMain {
+		t.Fatal("a key found in the ghost queue should be inserted into the main queue")
+	}
+	if p.ghost.Has(0) {
+		t.Fatal("0 should have been removed from the ghost queue")
+	}
+}
+
+func TestS3FIFOWeightedSegments(t *testing.T) {
+	// the size passed to New does not limit a weighted cache
+	gc := New[int, int](10).S3FIFO().MaximumWeight(1000).Build()
+	p := gc.(*S3FIFOCache[int, int]).policy.(*s3FIFOPolicy[int])
+
+	for i := 0; i < 1000; i++ {
+		gc.Set(i, i)
+	}
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 500; i++ {
+			gc.Get(i)
+		}
+	}
+	for i := 1000; i < 3000; i++ {
+		gc.Set(i, i)
+	}
+
+	if l := gc.Len(false); l != 1000 {
+		t.Fatalf("%v != 1000", l)
+	}
+	if p.size != 1000 || p.smallSize != 100 || p.main.Len() < 500 {
+		t.Fatalf("unexpected queues: size %v, small %v of %v, main %v", p.size, p.small.Len(), p.smallSize, p.main.Len())
+	}
+	for i := 0; i < 500; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("frequently accessed key %v should have been moved to the main queue", i)
+		}
+	}
+}
+
+func TestS3FIFOUpdateNotCounted(t *testing.T) {
+	size := 10
+	gc := buildTestCache[int, int](t, TYPE_S3FIFO, size)
+
+	for i := 0; i < size; i++ {
+		gc.Set(i, i)
+	}
+	// updates do not count as accesses, so 0 stays a one-hit wonder
+	for i := 0; i < 2; i++ {
+		gc.Set(0, i)
+	}
+	for i := size; i < 3*size; i++ {
+		gc.Set(i, i)
+	}
+	if gc.Has(0) {
+		t.Fatal("the key which has only been updated should have been evicted")
+	}
+}
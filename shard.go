@@ -0,0 +1,275 @@
+package gcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ShardedCache splits the keys over independent caches, each with its own
+// lock, to reduce lock contention. The shard of a key is selected by its hash.
+type ShardedCache[K comparable, V any] struct {
+	hasher hasher[K]
+	shards []Cache[K, V]
+}
+
+var _ Cache[int, int] = (*ShardedCache[int, int])(nil)
+
+func newShardedCache[K comparable, V any](cb *CacheBuilder[K, V]) *ShardedCache[K, V] {
+	// every shard holds at least one item
+	n := cb.shards
+	if cb.size > 0 {
+		n = min(n, cb.size)
+	}
+	if cb.maximumWeight > 0 && cb.maximumWeight < int64(n) {
+		n = int(cb.maximumWeight)
+	}
+	c := &ShardedCache[K, V]{
+		hasher: newHasher[K](),
+		shards: make([]Cache[K, V], n),
+	}
+	shardBuilder := *cb
+	shardBuilder.shards = 0
+	// an entry only needs to fit into the whole cache
+	shardBuilder.maximumEntryWeight = cb.maximumWeight
+	// the shards share the loader, so they share its circuit breaker
+	shardBuilder.breaker = cb.breaker.instance(cb.clock)
+	shardBuilder.limiter = cb.limiter.instance()
+	shardBuilder.hedger = cb.hedger.instance()
+	for i := range c.shards {
+		shardBuilder.size = shardSize(cb.size, n, i)
+		shardBuilder.maximumWeight = shardSize(cb.maximumWeight, n, i)
+		c.shards[i] = shardBuilder.build()
+	}
+	return c
+}
+
+// shardSize returns the share of shard i when total is divided over n
+// shards. The first total%n shards get one more, so the shares add up to
+// total.
+func shardSize[T int | int64](total T, n, i int) T {
+	if total <= 0 {
+		return total
+	}
+	size := total / T(n)
+	if T(i) < total%T(n) {
+		size++
+	}
+	return size
+}
+
+// shardResizer is implemented by the caches built as shards.
+type shardResizer interface {
+	resize(newSize int, maximumEntryWeight int64)
+}
+
+func (c *ShardedCache[K, V]) shard(key K) Cache[K, V] {
+	return c.shards[c.hasher.Hash(key)%uint64(len(c.shards))]
+}
+
+// Set a new key-value pair
+func (c *ShardedCache[K, V]) Set(key K, value V) error {
+	return c.shard(key).Set(key, value)
+}
+
+// SetWithExpire Set a new key-value pair with an expiration time
+func (c *ShardedCache[K, V]) SetWithExpire(key K, value V, expiration time.Duration) error {
+	return c.shard(key).SetWithExpire(key, value, expiration)
+}
+
+// Get a value from cache pool using key if it exists. If it does not exists key
+// and has LoaderFunc, generate a value using `LoaderFunc` method returns value.
+func (c *ShardedCache[K, V]) Get(key K) (V, error) {
+	return c.shard(key).Get(key)
+}
+
+// GetIFPresent gets a value from cache pool using key if it exists. If it does
+// not exists key, returns KeyNotFoundError. And send a request which refresh
+// value for specified key if cache object has LoaderFunc.
+func (c *ShardedCache[K, V]) GetIFPresent(key K) (V, error) {
+	return c.shard(key).GetIFPresent(key)
+}
+
+func (c *ShardedCache[K, V]) GetWithContext(ctx context.Context, key K) (V, error) {
+	return c.shard(key).GetWithContext(ctx, key)
+}
+
+func (c *ShardedCache[K, V]) GetIFPresentWithContext(ctx context.Context, key K) (V, error) {
+	return c.shard(key).GetIFPresentWithContext(ctx, key)
+}
+
+func (c *ShardedCache[K, V]) get(key K, onLoad bool) (V, error) {
+	return c.shard(key).get(key, onLoad)
+}
+
+// Has checks if key exists in cache
+func (c *ShardedCache[K, V]) Has(key K) bool {
+	return c.shard(key).Has(key)
+}
+
+// Remove removes the provided key from the cache.
+func (c *ShardedCache[K, V]) Remove(key K) bool {
+	return c.shard(key).Remove(key)
+}
+
+// GetMany groups the keys by shard and gets them from all shards
+// concurrently, so that every shard loads its missing keys with one call of
+// the BulkLoaderFunc.
+func (c *ShardedCache[K, V]) GetMany(ctx context.Context, keys []K) (map[K]V, error) {
+	shardKeys := make(map[Cache[K, V]][]K)
+	for _, key := range keys {
+		shard := c.shard(key)
+		shardKeys[shard] = append(shardKeys[shard], key)
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		values = make(map[K]V, len(keys))
+		err    error
+	)
+	for shard, keys := range shardKeys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, e := shard.GetMany(ctx, keys)
+			mu.Lock()
+			defer mu.Unlock()
+			if e != nil && err == nil {
+				err = e
+			}
+			for k, v := range m {
+				values[k] = v
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// GetALL returns all key-value pairs in the cache.
+func (c *ShardedCache[K, V]) GetALL(checkExpired bool) map[K]V {
+	items := make(map[K]V)
+	for _, shard := range c.shards {
+		for k, v := range shard.GetALL(checkExpired) {
+			items[k] = v
+		}
+	}
+	return items
+}
+
+// Keys returns a slice of the keys in the cache.
+func (c *ShardedCache[K, V]) Keys(checkExpired bool) []K {
+	var keys []K
+	for _, shard := range c.shards {
+		keys = append(keys, shard.Keys(checkExpired)...)
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *ShardedCache[K, V]) Len(checkExpired bool) int {
+	var length int
+	for _, shard := range c.shards {
+		length += shard.Len(checkExpired)
+	}
+	return length
+}
+
+// RemoveByTag removes the keys tagged with tag from all shards.
+func (c *ShardedCache[K, V]) RemoveByTag(tag string) int {
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.RemoveByTag(tag)
+	}
+	return removed
+}
+
+// Purge Completely clear the cache
+func (c *ShardedCache[K, V]) Purge() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// Resize divides the new size, or maximum weight, evenly over the shards. The
+// new size must not be smaller than the number of shards.
+func (c *ShardedCache[K, V]) Resize(newSize int) {
+	if newSize > 0 && newSize < len(c.shards) {
+		panic("gcache: Cache size < number of shards")
+	}
+	for i, shard := range c.shards {
+		// an entry only needs to fit into the whole cache
+		shard.(shardResizer).resize(shardSize(newSize, len(c.shards), i), int64(newSize))
+	}
+}
+
+// Close stops the background cleanup of all shards.
+func (c *ShardedCache[K, V]) Close() {
+	for _, shard := range c.shards {
+		shard.Close()
+	}
+}
+
+// HitCount returns hit count
+func (c *ShardedCache[K, V]) HitCount() uint64 {
+	var count uint64
+	for _, shard := range c.shards {
+		count += shard.HitCount()
+	}
+	return count
+}
+
+// MissCount returns miss count
+func (c *ShardedCache[K, V]) MissCount() uint64 {
+	var count uint64
+	for _, shard := range c.shards {
+		count += shard.MissCount()
+	}
+	return count
+}
+
+// NegativeHitCount returns negative hit count
+func (c *ShardedCache[K, V]) NegativeHitCount() uint64 {
+	var count uint64
+	for _, shard := range c.shards {
+		count += shard.NegativeHitCount()
+	}
+	return count
+}
+
+// RetryCount returns retry count
+func (c *ShardedCache[K, V]) RetryCount() uint64 {
+	var count uint64
+	for _, shard := range c.shards {
+		count += shard.RetryCount()
+	}
+	return count
+}
+
+// StageHitCount returns stage hit count
+func (c *ShardedCache[K, V]) StageHitCount(stage int) uint64 {
+	var count uint64
+	for _, shard := range c.shards {
+		count += shard.StageHitCount(stage)
+	}
+	return count
+}
+
+// LookupCount returns lookup count
+func (c *ShardedCache[K, V]) LookupCount() uint64 {
+	return c.HitCount() + c.MissCount()
+}
+
+// HitRate returns rate for cache hitting
+func (c *ShardedCache[K, V]) HitRate() float64 {
+	hc, mc := c.HitCount(), c.MissCount()
+	total := hc + mc
+	if total == 0 {
+		return 0.0
+	}
+	return float64(hc) / float64(total)
+}
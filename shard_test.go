@@ -0,0 +1,209 @@
+package gcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestShardedGet(t *testing.T) {
+	tps := []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_TINYLFU,
+		TYPE_S3FIFO,
+		TYPE_SIEVE,
+	}
+	for _, tp := range tps {
+		t.Run(tp, func(t *testing.T) {
+			size := 1000
+			gc := New[string, string](size).
+				EvictType(tp).
+				Shards(8).
+				Build()
+			if _, ok := gc.(*ShardedCache[string, string]); !ok {
+				t.Fatalf("unexpected cache type %T", gc)
+			}
+			testSetCache(t, gc, size/2)
+			testGetCache(t, gc, size/2)
+			if l := gc.Len(false); l != size/2 {
+				t.Errorf("%v != %v", l, size/2)
+			}
+		})
+	}
+}
+
+func TestShardedAggregates(t *testing.T) {
+	gc := New[int, int](256).
+		LRU().
+		Shards(4).
+		LoaderFunc(getter).
+		Build()
+
+	setItemsByRange(t, gc, 0, 32)
+	checkItemsByRange(t, gc.Keys(false), gc.GetALL(false), gc.Len(false), 0, 32)
+
+	for i := 0; i < 32; i++ {
+		gc.Get(i)
+	}
+	for i := 100; i < 132; i++ {
+		gc.Get(i)
+	}
+	if hc := gc.HitCount(); hc != 32 {
+		t.Errorf("%v != 32", hc)
+	}
+	if mc := gc.MissCount(); mc != 32 {
+		t.Errorf("%v != 32", mc)
+	}
+	if rate := gc.HitRate(); rate != 0.5 {
+		t.Errorf("%v != 0.5", rate)
+	}
+
+	if !gc.Remove(0) || gc.Has(0) {
+		t.Error("0 should have been removed")
+	}
+	gc.Purge()
+	if l := gc.Len(false); l != 0 {
+		t.Errorf("%v != 0", l)
+	}
+}
+
+func TestShardedResize(t *testing.T) {
+	gc := New[int, int](64).LRU().Shards(4).Build()
+	setItemsByRange(t, gc, 0, 200)
+	if l := gc.Len(false); l > 64 {
+		t.Fatalf("%v > 64", l)
+	}
+	gc.Resize(8)
+	if l := gc.Len(false); l > 8 {
+		t.Fatalf("%v > 8", l)
+	}
+
+	gc = New[int, int](64).
+		LRU().
+		Shards(4).
+		MaximumWeight(64).
+		Weigher(func(_ int, v int) int64 {
+			return int64(v)
+		}).
+		Build()
+	for i := 0; i < 200; i++ {
+		gc.Set(i, 1)
+	}
+	gc.Resize(10)
+	if l := gc.Len(false); l != 10 {
+		t.Fatalf("%v != 10", l)
+	}
+	if err := gc.Set(-1, 10); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := gc.Set(-2, 11); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestShardedCapacity(t *testing.T) {
+	// more shards than items
+	gc := New[int, int](10).LRU().Shards(16).Build()
+	setItemsByRange(t, gc, 0, 100)
+	if l := gc.Len(false); l != 10 {
+		t.Fatalf("%v != 10", l)
+	}
+
+	// the shares of the shards add up to the maximum weight
+	gc = New[int, int](100).
+		LRU().
+		Shards(3).
+		MaximumWeight(100).
+		Weigher(func(_ int, v int) int64 {
+			return int64(v)
+		}).
+		Build()
+	for i := 0; i < 1000; i++ {
+		gc.Set(i, 1)
+	}
+	if l := gc.Len(false); l != 100 {
+		t.Fatalf("%v != 100", l)
+	}
+
+	// an entry only needs to fit into the whole cache
+	if err := gc.Set(-1, 30); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if v, err := gc.Get(-1); err != nil || v != 30 {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	var tooHeavy *EntryTooHeavyError
+	if err := gc.Set(-2, 101); !errors.As(err, &tooHeavy) || tooHeavy.MaximumWeight != 100 {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestShardedConcurrentAccess(t *testing.T) {
+	gc := New[string, string](128).
+		LRU().
+		Shards(16).
+		LoaderFunc(loader[string, string]).
+		Build()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprintf("Key-%d", (i*j)%256)
+				v, err := gc.GetWithContext(context.Background(), key)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if expected, _ := loader[string, string](context.Background(), key); v != expected {
+					t.Errorf("%v != %v", v, expected)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestShardedGetMany(t *testing.T) {
+	var mu sync.Mutex
+	var calls, loaded int
+	gc := New[int, int](256).
+		LRU().
+		Shards(4).
+		BulkLoaderFunc(func(_ context.Context, keys []int) (map[int]int, error) {
+			mu.Lock()
+			calls++
+			loaded += len(keys)
+			mu.Unlock()
+			values := make(map[int]int, len(keys))
+			for _, key := range keys {
+				values[key] = key
+			}
+			return values, nil
+		}).
+		Build()
+
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+	values, err := gc.GetMany(context.Background(), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 100 || loaded != 100 {
+		t.Fatalf("%v, %v != 100", len(values), loaded)
+	}
+	// one bulk call per shard
+	if calls > 4 {
+		t.Fatalf("%v > 4", calls)
+	}
+}
@@ -0,0 +1,95 @@
+package gcache
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// SieveCache keeps items in a single FIFO queue with a visited bit per item.
+// A hand moves from the oldest towards the newest item, clearing visited bits
+// on its way, and evicts the first item which has not been visited. A hit
+// only sets the visited bit, so no list is reordered on Get.
+type SieveCache[K comparable, V any] struct {
+	policyCache[K, V]
+}
+
+func newSieveCache[K comparable, V any](cb *CacheBuilder[K, V]) *SieveCache[K, V] {
+	c := &SieveCache[K, V]{}
+	buildPolicyCache(&c.policyCache, cb, newSievePolicy[K](cb.size))
+	return c
+}
+
+type sievePolicy[K comparable] struct {
+	size  int
+	items map[K]*list.Element
+	queue *list.List
+	hand  *list.Element
+}
+
+type sieveEntry[K comparable] struct {
+	key     K
+	visited int32
+}
+
+func newSievePolicy[K comparable](size int) *sievePolicy[K] {
+	p := &sievePolicy[K]{size: size}
+	p.Reset()
+	return p
+}
+
+func (p *sievePolicy[K]) OnInsert(key K) {
+	p.items[key] = p.queue.PushFront(&sieveEntry[K]{key: key})
+}
+
+// OnAccess marks the key as visited since the hand last passed it. It only
+// reads the items map, so it is safe to call under the read lock.
+func (p *sievePolicy[K]) OnAccess(key K) {
+	elt, ok := p.items[key]
+	if !ok {
+		return
+	}
+	entry := elt.Value.(*sieveEntry[K])
+	if atomic.LoadInt32(&entry.visited) == 0 {
+		atomic.StoreInt32(&entry.visited, 1)
+	}
+}
+
+func (p *sievePolicy[K]) OnRemove(key K) {
+	elt, ok := p.items[key]
+	if !ok {
+		return
+	}
+	if p.hand == elt {
+		p.hand = elt.Prev()
+	}
+	p.queue.Remove(elt)
+	delete(p.items, key)
+}
+
+// Victim moves the hand towards newer items, clearing visited bits, and
+// returns the first key which has not been visited.
+func (p *sievePolicy[K]) Victim(incoming K) (K, bool) {
+	for p.queue.Len() > 0 {
+		if p.hand == nil {
+			p.hand = p.queue.Back()
+		}
+		entry := p.hand.Value.(*sieveEntry[K])
+		if atomic.CompareAndSwapInt32(&entry.visited, 1, 0) {
+			p.hand = p.hand.Prev()
+			continue
+		}
+		return entry.key, true
+	}
+	return incoming, false
+}
+
+func (p *sievePolicy[K]) Reset() {
+	p.queue = list.New()
+	p.items = make(map[K]*list.Element, p.size+1)
+	p.hand = nil
+}
+
+// ConcurrentAccess reports that OnAccess only sets an atomic visited bit.
+func (p *sievePolicy[K]) ConcurrentAccess() bool {
+	return true
+}
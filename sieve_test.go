@@ -0,0 +1,116 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSieveGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache[string, string](t, TYPE_SIEVE, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingSieveGet(t *testing.T) {
+	size := 1000
+	gc := buildTestLoadingCache(t, TYPE_SIEVE, size, loader)
+	testGetCache(t, gc, size)
+}
+
+func TestSieveLength(t *testing.T) {
+	gc := buildTestLoadingCache(t, TYPE_SIEVE, 1000, loader)
+	gc.Get("test1")
+	gc.Get("test2")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", length, expectedLength)
+	}
+}
+
+func TestSieveEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := 11
+	gc := buildTestLoadingCache(t, TYPE_SIEVE, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func TestSieveGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_SIEVE)
+}
+
+func TestSieveHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration[string, string](t, TYPE_SIEVE, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestSieveEvictUnvisited(t *testing.T) {
+	size := 4
+	gc := buildTestCache[int, int](t, TYPE_SIEVE, size)
+
+	for i := 0; i < size; i++ {
+		gc.Set(i, i)
+	}
+	gc.Get(0)
+	gc.Get(2)
+
+	// the hand skips the visited keys 0 and 2 and evicts 1, then 3
+	gc.Set(4, 4)
+	if gc.Has(1) {
+		t.Fatal("should not have 1")
+	}
+	gc.Set(5, 5)
+	if gc.Has(3) {
+		t.Fatal("should not have 3")
+	}
+	for _, k := range []int{0, 2, 4, 5} {
+		if !gc.Has(k) {
+			t.Fatalf("should have %v", k)
+		}
+	}
+
+	// the hand continues where it stopped, so 4 goes before 0
+	gc.Set(6, 6)
+	if gc.Has(4) {
+		t.Fatal("should not have 4")
+	}
+	if !gc.Has(0) {
+		t.Fatal("should have 0")
+	}
+}
@@ -1,317 +1,55 @@
 package gcache
 
-import (
-	"context"
-	"errors"
-	"time"
-)
-
 // SimpleCache has no clear priority for evict cache. It depends on key-value
 // map order.
 type SimpleCache[K comparable, V any] struct {
-	baseCache[K, V]
-	items map[K]*simpleItem[V]
+	policyCache[K, V]
 }
 
 func newSimpleCache[K comparable, V any](cb *CacheBuilder[K, V]) *SimpleCache[K, V] {
 	c := &SimpleCache[K, V]{}
-	buildCache(&c.baseCache, cb)
-
-	c.init()
-	c.loadGroup.cache = c
+	buildPolicyCache(&c.policyCache, cb, newSimplePolicy[K](cb.size))
 	return c
 }
 
-func (c *SimpleCache[K, V]) init() {
-	if c.size <= 0 {
-		c.items = make(map[K]*simpleItem[V])
-	} else {
-		c.items = make(map[K]*simpleItem[V], c.size)
-	}
-}
-
-// Set a new key-value pair
-func (c *SimpleCache[K, V]) Set(key K, value V) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	_, err := c.set(key, value)
-	return err
-}
-
-// Set a new key-value pair with an expiration time
-func (c *SimpleCache[K, V]) SetWithExpire(key K, value V, expiration time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	item, err := c.set(key, value)
-	if err != nil {
-		return err
-	}
-
-	t := c.clock.Now().Add(expiration)
-	item.expiration = &t
-	return nil
-}
-
-func (c *SimpleCache[K, V]) set(key K, value V) (*simpleItem[V], error) {
-	var err error
-	if c.serializeFunc != nil {
-		value, err = c.serializeFunc(key, value)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Check for existing item
-	item, ok := c.items[key]
-	if ok {
-		item.value = value
-	} else {
-		// Verify size not exceeded
-		if (len(c.items) >= c.size) && c.size > 0 {
-			c.evict(1)
-		}
-		item = &simpleItem[V]{
-			clock: c.clock,
-			value: value,
-		}
-		c.items[key] = item
-	}
-
-	if c.expiration != nil {
-		t := c.clock.Now().Add(*c.expiration)
-		item.expiration = &t
-	}
-
-	if c.addedFunc != nil {
-		c.addedFunc(key, value)
-	}
-
-	return item, nil
-}
-
-// Get a value from cache pool using key if it exists. If it does not exists key
-// and has LoaderFunc, generate a value using `LoaderFunc` method returns value.
-func (c *SimpleCache[K, V]) Get(key K) (V, error) {
-	return c.GetWithContext(context.Background(), key)
+// simplePolicy evicts an arbitrary key.
+type simplePolicy[K comparable] struct {
+	size int
+	keys map[K]struct{}
 }
 
-// GetIFPresent gets a value from cache pool using key if it exists. If it does
-// not exists key, returns KeyNotFoundError. And send a request which refresh
-// value for specified key if cache object has LoaderFunc.
-func (c *SimpleCache[K, V]) GetIFPresent(key K) (V, error) {
-	return c.GetIFPresentWithContext(context.Background(), key)
+func newSimplePolicy[K comparable](size int) *simplePolicy[K] {
+	p := &simplePolicy[K]{size: size}
+	p.Reset()
+	return p
 }
 
-func (c *SimpleCache[K, V]) GetWithContext(ctx context.Context, key K) (V, error) {
-	v, err := c.get(key, false)
-	if errors.Is(err, KeyNotFoundError) {
-		return c.getWithLoader(ctx, key, true)
-	}
-	return v, err
+func (p *simplePolicy[K]) OnInsert(key K) {
+	p.keys[key] = struct{}{}
 }
 
-func (c *SimpleCache[K, V]) GetIFPresentWithContext(ctx context.Context, key K) (V, error) {
-	v, err := c.get(key, false)
-	if errors.Is(err, KeyNotFoundError) {
-		return c.getWithLoader(ctx, key, false)
-	}
-	return v, nil
-}
+func (p *simplePolicy[K]) OnAccess(key K) {}
 
-func (c *SimpleCache[K, V]) get(key K, onLoad bool) (v V, _ error) {
-	v, err := c.getValue(key, onLoad)
-	if err != nil {
-		return v, err
-	}
-	if c.deserializeFunc != nil {
-		return c.deserializeFunc(key, v)
-	}
-	return v, nil
-}
-
-func (c *SimpleCache[K, V]) getValue(key K, onLoad bool) (v V, _ error) {
-	c.mu.Lock()
-	item, ok := c.items[key]
-	if ok {
-		if !item.IsExpired(nil) {
-			v := item.value
-			c.mu.Unlock()
-			if !onLoad {
-				c.stats.IncrHitCount()
-			}
-			return v, nil
-		}
-		c.remove(key)
-	}
-	c.mu.Unlock()
-	if !onLoad {
-		c.stats.IncrMissCount()
-	}
-	return v, KeyNotFoundError
+func (p *simplePolicy[K]) OnRemove(key K) {
+	delete(p.keys, key)
 }
 
-func (c *SimpleCache[K, V]) getWithLoader(ctx context.Context, key K, isWait bool) (v V, _ error) {
-	if c.loaderExpireFunc == nil {
-		return v, KeyNotFoundError
+func (p *simplePolicy[K]) Victim(incoming K) (K, bool) {
+	for key := range p.keys {
+		return key, true
 	}
-	value, _, err := c.load(ctx, key, func(v V, expiration *time.Duration, e error) (ret V, _ error) {
-		if e != nil {
-			return ret, e
-		}
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		item, err := c.set(key, v)
-		if err != nil {
-			return ret, err
-		}
-		if expiration != nil {
-			t := c.clock.Now().Add(*expiration)
-			item.expiration = &t
-		}
-		return v, nil
-	}, isWait)
-	if err != nil {
-		return v, err
-	}
-	return value, nil
+	return incoming, false
 }
 
-func (c *SimpleCache[K, V]) evict(count int) {
-	now := c.clock.Now()
-	current := 0
-	for key, item := range c.items {
-		if current >= count {
-			return
-		}
-		if item.expiration == nil || now.After(*item.expiration) {
-			defer c.remove(key)
-			current++
-		}
-	}
-}
-
-// Has checks if key exists in cache
-func (c *SimpleCache[K, V]) Has(key K) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	now := time.Now()
-	return c.has(key, &now)
-}
-
-func (c *SimpleCache[K, V]) has(key K, now *time.Time) bool {
-	item, ok := c.items[key]
-	if !ok {
-		return false
-	}
-	return !item.IsExpired(now)
-}
-
-// Remove removes the provided key from the cache.
-func (c *SimpleCache[K, V]) Remove(key K) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	return c.remove(key)
-}
-
-func (c *SimpleCache[K, V]) remove(key K) bool {
-	item, ok := c.items[key]
-	if ok {
-		delete(c.items, key)
-		if c.evictedFunc != nil {
-			c.evictedFunc(key, item.value)
-		}
-		return true
-	}
-	return false
-}
-
-// Returns a slice of the keys in the cache.
-func (c *SimpleCache[K, V]) keys() []K {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]K, len(c.items))
-	i := 0
-	for k := range c.items {
-		keys[i] = k
-		i++
-	}
-	return keys
-}
-
-// GetALL returns all key-value pairs in the cache.
-func (c *SimpleCache[K, V]) GetALL(checkExpired bool) map[K]V {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	items := make(map[K]V, len(c.items))
-	now := time.Now()
-	for k, item := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			items[k] = item.value
-		}
-	}
-	return items
-}
-
-// Keys returns a slice of the keys in the cache.
-func (c *SimpleCache[K, V]) Keys(checkExpired bool) []K {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]K, 0, len(c.items))
-	now := time.Now()
-	for k := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			keys = append(keys, k)
-		}
-	}
-	return keys
-}
-
-// Len returns the number of items in the cache.
-func (c *SimpleCache[K, V]) Len(checkExpired bool) int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if !checkExpired {
-		return len(c.items)
-	}
-	var length int
-	now := time.Now()
-	for k := range c.items {
-		if c.has(k, &now) {
-			length++
-		}
-	}
-	return length
-}
-
-// Completely clear the cache
-func (c *SimpleCache[K, V]) Purge() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.purgeVisitorFunc != nil {
-		for key, item := range c.items {
-			c.purgeVisitorFunc(key, item.value)
-		}
+func (p *simplePolicy[K]) Reset() {
+	if p.size <= 0 {
+		p.keys = make(map[K]struct{})
+	} else {
+		p.keys = make(map[K]struct{}, p.size)
 	}
-
-	c.init()
 }
 
-type simpleItem[V any] struct {
-	clock      Clock
-	value      V
-	expiration *time.Time
-}
-
-// IsExpired returns boolean value whether this item is expired or not.
-func (si *simpleItem[V]) IsExpired(now *time.Time) bool {
-	if si.expiration == nil {
-		return false
-	}
-	if now == nil {
-		t := si.clock.Now()
-		now = &t
-	}
-	return si.expiration.Before(*now)
+// ConcurrentAccess reports that hits need no bookkeeping at all.
+func (p *simplePolicy[K]) ConcurrentAccess() bool {
+	return true
 }
@@ -19,13 +19,28 @@ limitations under the License.
 // This module provides a duplicate function call suppression
 // mechanism.
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"sync"
+)
 
 // call is an in-flight or completed Do call
 type call[V any] struct {
-	wg  sync.WaitGroup
-	val V
-	err error
+	done chan struct{}
+	val  V
+	err  error
+
+	// waiters is the number of callers waiting for the call, protected by
+	// Group.mu
+	waiters int
+	// cancel cancels the context of the call if the group cancels abandoned
+	// calls
+	cancel context.CancelFunc
+}
+
+func newCall[V any]() *call[V] {
+	return &call[V]{done: make(chan struct{})}
 }
 
 // Group represents a class of work and forms a namespace in which units of work
@@ -34,13 +49,21 @@ type Group[K comparable, V any] struct {
 	cache Cache[K,V]
 	mu    sync.Mutex            // protects m
 	m     map[K]*call[V] // lazily initialized
+
+	// cancelAbandoned cancels the context of a call once all its waiters
+	// have gone away
+	cancelAbandoned bool
+	// detach runs calls under a context which is not cancelled with the
+	// context of the first caller
+	detach bool
 }
 
 // Do executes and returns the results of the given function, making sure that
 // only one execution is in-flight for a given key at a time. If a duplicate
 // comes in, the duplicate caller waits for the original to complete and
-// receives the same results.
-func (g *Group[K,V]) Do(key K, fn func() (V, error), isWait bool) (V, bool, error) {
+// receives the same results. Every caller stops waiting once its ctx is done,
+// while the function keeps running for the other callers.
+func (g *Group[K,V]) Do(ctx context.Context, key K, fn func(context.Context) (V, error), isWait bool) (V, bool, error) {
 	g.mu.Lock()
 	v, err := g.cache.get(key, true)
 	if err == nil {
@@ -51,34 +74,183 @@ func (g *Group[K,V]) Do(key K, fn func() (V, error), isWait bool) (V, bool, erro
 		g.m = make(map[K]*call[V])
 	}
 	if c, ok := g.m[key]; ok {
-		g.mu.Unlock()
 		if !isWait {
+			g.mu.Unlock()
 			var v V
 			return v, false, KeyNotFoundError
 		}
-		c.wg.Wait()
-		return c.val, false, c.err
+		c.waiters++
+		g.mu.Unlock()
+		v, err := g.wait(ctx, key, c)
+		return v, false, err
+	}
+	c := newCall[V]()
+	loadCtx := ctx
+	if g.detach || g.cancelAbandoned {
+		loadCtx = context.WithoutCancel(ctx)
+	}
+	if g.cancelAbandoned {
+		loadCtx, c.cancel = context.WithCancel(loadCtx)
+	}
+	if isWait {
+		c.waiters = 1
 	}
-	c := new(call[V])
-	c.wg.Add(1)
 	g.m[key] = c
 	g.mu.Unlock()
+	go g.call(loadCtx, c, key, fn)
 	if !isWait {
-		go g.call(c, key, fn)
 		var v V
 		return v, false, KeyNotFoundError
 	}
-	v, err = g.call(c, key, fn)
+	v, err = g.wait(ctx, key, c)
 	return v, true, err
 }
 
-func (g *Group[K,V]) call(c *call[V], key K, fn func() (V, error)) (V, error) {
-	c.val, c.err = fn()
-	c.wg.Done()
+// wait waits for c to complete or for ctx to be done.
+func (g *Group[K,V]) wait(ctx context.Context, key K, c *call[V]) (V, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+	}
+	g.leave(key, c)
+	var v V
+	return v, ctx.Err()
+}
+
+// leave releases a waiter of c. The last waiter leaving a call before it
+// completes cancels it, if the group cancels abandoned calls. An abandoned
+// call is forgotten, so that later callers start a new one.
+func (g *Group[K,V]) leave(key K, c *call[V]) {
+	g.mu.Lock()
+	c.waiters--
+	abandoned := c.waiters == 0 && c.cancel != nil
+	if abandoned && g.m[key] == c {
+		delete(g.m, key)
+	}
+	g.mu.Unlock()
+	if abandoned {
+		c.cancel()
+	}
+}
+
+func (g *Group[K,V]) call(ctx context.Context, c *call[V], key K, fn func(context.Context) (V, error)) {
+	c.val, c.err = fn(ctx)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	close(c.done)
+
+	g.mu.Lock()
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
+	g.mu.Unlock()
+}
+
+// refresh executes fn in the background, unless a call for key is in flight.
+// Unlike Do, it does not check the cache first, so that a present value can
+// be replaced. Do callers for a key missing from the cache wait for the
+// refresh to complete.
+func (g *Group[K,V]) refresh(ctx context.Context, key K, fn func(context.Context) (V, error)) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	if _, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		return
+	}
+	c := newCall[V]()
+	g.m[key] = c
+	g.mu.Unlock()
+	go g.call(ctx, c, key, fn)
+}
+
+// doMany is like Do for several keys. Keys present in the cache are returned
+// directly and keys with a call in flight are waited on. fn is called once
+// with all other keys, and its results are shared with duplicate callers of
+// those keys. Keys which are not found are missing from the result.
+func (g *Group[K,V]) doMany(ctx context.Context, keys []K, fn func(context.Context, []K) (map[K]V, error)) (map[K]V, error) {
+	values := make(map[K]V, len(keys))
+	waits := make(map[K]*call[V])
+	own := make(map[K]*call[V])
+	var ownKeys []K
 
 	g.mu.Lock()
-	delete(g.m, key)
+	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if _, ok := own[key]; ok {
+			continue
+		}
+		if _, ok := waits[key]; ok {
+			continue
+		}
+		if v, err := g.cache.get(key, true); err == nil {
+			values[key] = v
+			continue
+		}
+		if c, ok := g.m[key]; ok {
+			c.waiters++
+			waits[key] = c
+			continue
+		}
+		c := newCall[V]()
+		g.m[key] = c
+		own[key] = c
+		ownKeys = append(ownKeys, key)
+	}
 	g.mu.Unlock()
 
-	return c.val, c.err
+	if len(ownKeys) > 0 {
+		loadCtx := ctx
+		if g.detach {
+			loadCtx = context.WithoutCancel(ctx)
+		}
+		loaded, err := fn(loadCtx, ownKeys)
+		for key, c := range own {
+			if err != nil {
+				c.err = err
+			} else if v, ok := loaded[key]; ok {
+				c.val = v
+			} else {
+				c.err = KeyNotFoundError
+			}
+			close(c.done)
+		}
+		g.mu.Lock()
+		for key, c := range own {
+			if g.m[key] == c {
+				delete(g.m, key)
+			}
+		}
+		g.mu.Unlock()
+		if err != nil {
+			for key, c := range waits {
+				g.leave(key, c)
+			}
+			return nil, err
+		}
+		for key, c := range own {
+			if c.err == nil {
+				values[key] = c.val
+			}
+		}
+	}
+
+	var err error
+	for key, c := range waits {
+		v, e := g.wait(ctx, key, c)
+		if e == nil {
+			values[key] = v
+		} else if !errors.Is(e, KeyNotFoundError) && err == nil {
+			err = e
+		}
+	}
+	return values, err
 }
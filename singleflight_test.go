@@ -17,6 +17,7 @@ limitations under the License.
 */
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -28,7 +29,7 @@ import (
 func TestDo(t *testing.T) {
 	var g Group[string, string]
 	g.cache = New[string, string](32).Build()
-	v, _, err := g.Do("key", func() (string, error) {
+	v, _, err := g.Do(context.Background(), "key", func(context.Context) (string, error) {
 		return "bar", nil
 	}, true)
 	if got, want := fmt.Sprintf("%v (%T)", v, v), "bar (string)"; got != want {
@@ -43,7 +44,7 @@ func TestDoErr(t *testing.T) {
 	var g Group[string, string]
 	g.cache = New[string, string](32).Build()
 	someErr := errors.New("some error")
-	v, _, err := g.Do("key", func() (string, error) {
+	v, _, err := g.Do(context.Background(), "key", func(context.Context) (string, error) {
 		return "", someErr
 	}, true)
 	if !errors.Is(err, someErr) {
@@ -59,7 +60,7 @@ func TestDoDupSuppress(t *testing.T) {
 	g.cache = New[string, string](32).Build()
 	c := make(chan string)
 	var calls int32
-	fn := func() (string, error) {
+	fn := func(context.Context) (string, error) {
 		atomic.AddInt32(&calls, 1)
 		return <-c, nil
 	}
@@ -69,7 +70,7 @@ func TestDoDupSuppress(t *testing.T) {
 	for i := 0; i < n; i++ {
 		wg.Add(1)
 		go func() {
-			v, _, err := g.Do("key", fn, true)
+			v, _, err := g.Do(context.Background(), "key", fn, true)
 			if err != nil {
 				t.Errorf("Do error: %v", err)
 			}
@@ -86,3 +87,76 @@ func TestDoDupSuppress(t *testing.T) {
 		t.Errorf("number of calls = %d; want 1", got)
 	}
 }
+
+func TestDoContext(t *testing.T) {
+	var g Group[string, string]
+	g.cache = New[string, string](32).Build()
+	release := make(chan struct{})
+	fn := func(context.Context) (string, error) {
+		<-release
+		return "bar", nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if v, _, err := g.Do(context.Background(), "key", fn, true); err != nil || v != "bar" {
+			t.Errorf("Do = %v, %v", v, err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// a waiter gives up when its context is done, the call goes on
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := g.Do(ctx, "key", fn, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do error = %v; want DeadlineExceeded", err)
+	}
+	close(release)
+	<-done
+}
+
+func TestDoCancelAbandoned(t *testing.T) {
+	g := Group[string, string]{cancelAbandoned: true}
+	g.cache = New[string, string](32).Build()
+	cancelled := make(chan struct{})
+	var calls int32
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		close(cancelled)
+		return "", ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := g.Do(ctx, "key", fn, true); !errors.Is(err, context.Canceled) {
+				t.Errorf("Do error = %v; want Canceled", err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// the call goes on while a waiter is left
+	cancel1()
+	select {
+	case <-cancelled:
+		t.Fatal("call cancelled with a waiter left")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancel2()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("abandoned call not cancelled")
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("number of calls = %d; want 1", got)
+	}
+}
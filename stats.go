@@ -9,12 +9,18 @@ type statsAccessor interface {
 	MissCount() uint64
 	LookupCount() uint64
 	HitRate() float64
+	NegativeHitCount() uint64
+	RetryCount() uint64
+	StageHitCount(stage int) uint64
 }
 
 // statistics
 type stats struct {
-	hitCount  uint64
-	missCount uint64
+	hitCount         uint64
+	missCount        uint64
+	negativeHitCount uint64
+	retryCount       uint64
+	stageHitCounts   []uint64
 }
 
 // increment hit count
@@ -27,6 +33,21 @@ func (st *stats) IncrMissCount() uint64 {
 	return atomic.AddUint64(&st.missCount, 1)
 }
 
+// increment negative hit count
+func (st *stats) IncrNegativeHitCount() uint64 {
+	return atomic.AddUint64(&st.negativeHitCount, 1)
+}
+
+// increment retry count
+func (st *stats) IncrRetryCount() uint64 {
+	return atomic.AddUint64(&st.retryCount, 1)
+}
+
+// increment the count of values loaded by a stage of the loader chain
+func (st *stats) IncrStageHitCount(stage int) uint64 {
+	return atomic.AddUint64(&st.stageHitCounts[stage], 1)
+}
+
 // HitCount returns hit count
 func (st *stats) HitCount() uint64 {
 	return atomic.LoadUint64(&st.hitCount)
@@ -37,6 +58,26 @@ func (st *stats) MissCount() uint64 {
 	return atomic.LoadUint64(&st.missCount)
 }
 
+// NegativeHitCount returns the number of lookups answered with a cached loader
+// error. These lookups are counted as misses as well.
+func (st *stats) NegativeHitCount() uint64 {
+	return atomic.LoadUint64(&st.negativeHitCount)
+}
+
+// RetryCount returns the number of retried loader calls
+func (st *stats) RetryCount() uint64 {
+	return atomic.LoadUint64(&st.retryCount)
+}
+
+// StageHitCount returns the number of values loaded by the given stage of the
+// loader chain
+func (st *stats) StageHitCount(stage int) uint64 {
+	if stage < 0 || stage >= len(st.stageHitCounts) {
+		return 0
+	}
+	return atomic.LoadUint64(&st.stageHitCounts[stage])
+}
+
 // LookupCount returns lookup count
 func (st *stats) LookupCount() uint64 {
 	return st.HitCount() + st.MissCount()
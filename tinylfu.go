@@ -0,0 +1,277 @@
+package gcache
+
+import (
+	"container/list"
+)
+
+const (
+	tinyLFUWindowPercent    = 1
+	tinyLFUProtectedPercent = 80
+)
+
+const (
+	tinyLFUWindow uint8 = iota
+	tinyLFUProbation
+	tinyLFUProtected
+)
+
+// TinyLFUCache admits new items through a small LRU window and keeps a
+// segmented LRU main region. A frequency sketch decides whether an item
+// leaving the window may replace the main region's eviction victim.
+type TinyLFUCache[K comparable, V any] struct {
+	policyCache[K, V]
+}
+
+func newTinyLFUCache[K comparable, V any](cb *CacheBuilder[K, V]) *TinyLFUCache[K, V] {
+	c := &TinyLFUCache[K, V]{}
+	buildPolicyCache(&c.policyCache, cb, newTinyLFUPolicy[K](cb.size))
+	return c
+}
+
+type tinyLFUPolicy[K comparable] struct {
+	size      int
+	items     map[K]*tinyLFUEntry[K]
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	sketch    *frequencySketch[K]
+
+	windowSize    int
+	protectedSize int
+}
+
+type tinyLFUEntry[K comparable] struct {
+	key     K
+	segment uint8
+	element *list.Element
+}
+
+func newTinyLFUPolicy[K comparable](size int) *tinyLFUPolicy[K] {
+	p := &tinyLFUPolicy[K]{}
+	p.setSize(size)
+	p.Reset()
+	return p
+}
+
+func (p *tinyLFUPolicy[K]) setSize(size int) {
+	p.size = size
+	p.windowSize = max(1, size*tinyLFUWindowPercent/100)
+	p.protectedSize = (size - p.windowSize) * tinyLFUProtectedPercent / 100
+}
+
+func (p *tinyLFUPolicy[K]) OnInsert(key K) {
+	p.sketch.Increment(key)
+	entry := &tinyLFUEntry[K]{key: key, segment: tinyLFUWindow}
+	entry.element = p.window.PushFront(entry)
+	p.items[key] = entry
+	// while the main region has room, items leaving the window are admitted
+	for p.window.Len() > p.windowSize && p.mainLen() < p.size-p.windowSize {
+		candidate := p.window.Back().Value.(*tinyLFUEntry[K])
+		p.window.Remove(candidate.element)
+		p.pushProbation(candidate)
+	}
+}
+
+func (p *tinyLFUPolicy[K]) OnAccess(key K) {
+	entry, ok := p.items[key]
+	if !ok {
+		return
+	}
+	p.sketch.Increment(key)
+	switch entry.segment {
+	case tinyLFUWindow:
+		p.window.MoveToFront(entry.element)
+	case tinyLFUProbation:
+		p.probation.Remove(entry.element)
+		entry.segment = tinyLFUProtected
+		entry.element = p.protected.PushFront(entry)
+		if p.protected.Len() > p.protectedSize {
+			demoted := p.protected.Back().Value.(*tinyLFUEntry[K])
+			p.protected.Remove(demoted.element)
+			p.pushProbation(demoted)
+		}
+	case tinyLFUProtected:
+		p.protected.MoveToFront(entry.element)
+	}
+}
+
+func (p *tinyLFUPolicy[K]) OnRemove(key K) {
+	entry, ok := p.items[key]
+	if !ok {
+		return
+	}
+	p.segment(entry.segment).Remove(entry.element)
+	delete(p.items, key)
+}
+
+// Victim lets the oldest window item compete with the main region's victim
+// and returns the less frequently used one of both. A winning window item is
+// moved into the main region.
+func (p *tinyLFUPolicy[K]) Victim(incoming K) (K, bool) {
+	var candidate, victim *tinyLFUEntry[K]
+	if elt := p.window.Back(); elt != nil {
+		candidate = elt.Value.(*tinyLFUEntry[K])
+	}
+	if elt := p.probation.Back(); elt != nil {
+		victim = elt.Value.(*tinyLFUEntry[K])
+	} else if elt := p.protected.Back(); elt != nil {
+		victim = elt.Value.(*tinyLFUEntry[K])
+	}
+
+	switch {
+	case candidate == nil && victim == nil:
+		return incoming, false
+	case victim == nil:
+		return candidate.key, true
+	case candidate == nil:
+		return victim.key, true
+	case p.sketch.Estimate(candidate.key) <= p.sketch.Estimate(victim.key):
+		return candidate.key, true
+	}
+	p.window.Remove(candidate.element)
+	p.pushProbation(candidate)
+	return victim.key, true
+}
+
+// Resize rebalances the segments for the new size. A sketch which became too
+// small for the new size is widened, keeping the recorded frequencies.
+func (p *tinyLFUPolicy[K]) Resize(size int) {
+	p.setSize(size)
+	for p.protected.Len() > p.protectedSize {
+		demoted := p.protected.Back().Value.(*tinyLFUEntry[K])
+		p.protected.Remove(demoted.element)
+		p.pushProbation(demoted)
+	}
+	for p.window.Len() > p.windowSize {
+		candidate := p.window.Back().Value.(*tinyLFUEntry[K])
+		p.window.Remove(candidate.element)
+		p.pushProbation(candidate)
+	}
+	p.sketch.resize(size)
+}
+
+func (p *tinyLFUPolicy[K]) Reset() {
+	p.items = make(map[K]*tinyLFUEntry[K], p.size+1)
+	p.window = list.New()
+	p.probation = list.New()
+	p.protected = list.New()
+	p.sketch = newFrequencySketch[K](p.size)
+}
+
+func (p *tinyLFUPolicy[K]) pushProbation(entry *tinyLFUEntry[K]) {
+	entry.segment = tinyLFUProbation
+	entry.element = p.probation.PushFront(entry)
+}
+
+func (p *tinyLFUPolicy[K]) mainLen() int {
+	return p.probation.Len() + p.protected.Len()
+}
+
+func (p *tinyLFUPolicy[K]) segment(segment uint8) *list.List {
+	switch segment {
+	case tinyLFUProbation:
+		return p.probation
+	case tinyLFUProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+const (
+	sketchDepth      = 4
+	sketchWidthRate  = 4
+	sketchMaxCount   = 15
+	sketchSampleRate = 10
+)
+
+// frequencySketch is a count-min sketch estimating how often a key has been
+// seen. All counters are halved once the number of increments reaches the
+// sample size, so that the popularity of old keys fades over time.
+type frequencySketch[K comparable] struct {
+	hasher     hasher[K]
+	counters   []uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+func newFrequencySketch[K comparable](size int) *frequencySketch[K] {
+	width := sketchWidth(size)
+	return &frequencySketch[K]{
+		hasher:     newHasher[K](),
+		counters:   make([]uint8, sketchDepth*width),
+		mask:       width - 1,
+		sampleSize: sketchSampleRate * max(size, 1),
+	}
+}
+
+// sketchWidth returns the number of counters per row for size items.
+func sketchWidth(size int) uint64 {
+	width := uint64(16)
+	for width < uint64(sketchWidthRate*size) {
+		width <<= 1
+	}
+	return width
+}
+
+// resize adapts the sample size to size items and widens a sketch which is too
+// small for them. Every counter of the wider rows starts with the count of the
+// counter it was folded into before, so estimates stay the same.
+func (s *frequencySketch[K]) resize(size int) {
+	s.sampleSize = sketchSampleRate * max(size, 1)
+	width := sketchWidth(size)
+	if width <= s.mask+1 {
+		return
+	}
+	counters := make([]uint8, sketchDepth*width)
+	for row := uint64(0); row < sketchDepth; row++ {
+		for i := uint64(0); i < width; i++ {
+			counters[row*width+i] = s.counters[row*(s.mask+1)+i&s.mask]
+		}
+	}
+	s.counters = counters
+	s.mask = width - 1
+}
+
+func (s *frequencySketch[K]) index(hash uint64, row int) uint64 {
+	h1, h2 := hash&0xffffffff, hash>>32
+	return uint64(row)*(s.mask+1) + ((h1 + uint64(row)*h2) & s.mask)
+}
+
+// Increment records an occurrence of key.
+func (s *frequencySketch[K]) Increment(key K) {
+	hash := s.hasher.Hash(key)
+	added := false
+	for row := 0; row < sketchDepth; row++ {
+		i := s.index(hash, row)
+		if s.counters[i] < sketchMaxCount {
+			s.counters[i]++
+			added = true
+		}
+	}
+	if added {
+		s.additions++
+		if s.additions >= s.sampleSize {
+			s.reset()
+		}
+	}
+}
+
+// Estimate returns the estimated number of occurrences of key.
+func (s *frequencySketch[K]) Estimate(key K) uint8 {
+	hash := s.hasher.Hash(key)
+	freq := uint8(sketchMaxCount)
+	for row := 0; row < sketchDepth; row++ {
+		freq = min(freq, s.counters[s.index(hash, row)])
+	}
+	return freq
+}
+
+// reset ages the sketch by halving every counter.
+func (s *frequencySketch[K]) reset() {
+	for i := range s.counters {
+		s.counters[i] >>= 1
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,184 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTinyLFUGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache[string, string](t, TYPE_TINYLFU, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingTinyLFUGet(t *testing.T) {
+	size := 1000
+	gc := buildTestLoadingCache(t, TYPE_TINYLFU, size, loader)
+	testGetCache(t, gc, size)
+}
+
+func TestTinyLFULength(t *testing.T) {
+	gc := buildTestLoadingCache(t, TYPE_TINYLFU, 1000, loader)
+	gc.Get("test1")
+	gc.Get("test2")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", length, expectedLength)
+	}
+}
+
+func TestTinyLFUEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := 11
+	gc := buildTestLoadingCache(t, TYPE_TINYLFU, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestTinyLFUGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_TINYLFU)
+}
+
+func TestTinyLFUHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration[string, string](t, TYPE_TINYLFU, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestTinyLFUScanResistance(t *testing.T) {
+	size := 100
+	gc := buildTestCache[int, int](t, TYPE_TINYLFU, size)
+
+	hot := size / 2
+	for round := 0; round < 10; round++ {
+		for i := 0; i < hot; i++ {
+			if _, err := gc.Get(i); err != nil {
+				gc.Set(i, i)
+			}
+		}
+	}
+	// a one-off scan over many cold keys must not flush the hot keys
+	for i := size; i < 10*size; i++ {
+		gc.Set(i, i)
+	}
+
+	// the sketch may overestimate a cold key, so allow for a few collisions
+	var survived int
+	for i := 0; i < hot; i++ {
+		if gc.Has(i) {
+			survived++
+		}
+	}
+	if survived < hot*9/10 {
+		t.Errorf("only %v of %v hot keys survived a scan", survived, hot)
+	}
+	if l := gc.Len(false); l != size {
+		t.Errorf("%v != %v", l, size)
+	}
+}
+
+func TestTinyLFUWeightedSegments(t *testing.T) {
+	// the size passed to New does not limit a weighted cache
+	gc := New[int, int](10).TinyLFU().MaximumWeight(1000).Build()
+	p := gc.(*TinyLFUCache[int, int]).policy.(*tinyLFUPolicy[int])
+
+	hot := 500
+	for round := 0; round < 10; round++ {
+		for i := 0; i < hot; i++ {
+			if _, err := gc.Get(i); err != nil {
+				gc.Set(i, i)
+			}
+		}
+	}
+	for i := 1000; i < 10000; i++ {
+		gc.Set(i, i)
+	}
+
+	if l := gc.Len(false); l != 1000 {
+		t.Fatalf("%v != 1000", l)
+	}
+	if p.size != 1000 || p.window.Len() != p.windowSize || p.windowSize != 10 {
+		t.Fatalf("unexpected segments: size %v, window %v of %v", p.size, p.window.Len(), p.windowSize)
+	}
+	var survived int
+	for i := 0; i < hot; i++ {
+		if gc.Has(i) {
+			survived++
+		}
+	}
+	if survived < hot*9/10 {
+		t.Errorf("only %v of %v hot keys survived a scan", survived, hot)
+	}
+}
+
+func TestFrequencySketch(t *testing.T) {
+	s := newFrequencySketch[int](64)
+	for i := 0; i < 5; i++ {
+		s.Increment(1)
+	}
+	if f := s.Estimate(1); f < 5 {
+		t.Errorf("estimate %v < 5", f)
+	}
+	for i := 0; i < 100; i++ {
+		s.Increment(2)
+	}
+	if f := s.Estimate(2); f != sketchMaxCount {
+		t.Errorf("%v != %v", f, sketchMaxCount)
+	}
+
+	s.reset()
+	if f := s.Estimate(2); f != sketchMaxCount/2 {
+		t.Errorf("%v != %v", f, sketchMaxCount/2)
+	}
+
+	// widening keeps the estimates
+	before := s.Estimate(1)
+	s.resize(4096)
+	if s.mask+1 != sketchWidth(4096) {
+		t.Errorf("%v != %v", s.mask+1, sketchWidth(4096))
+	}
+	if f := s.Estimate(1); f != before {
+		t.Errorf("%v != %v", f, before)
+	}
+	if f := s.Estimate(2); f != sketchMaxCount/2 {
+		t.Errorf("%v != %v", f, sketchMaxCount/2)
+	}
+}
@@ -0,0 +1,150 @@
+package gcache
+
+import (
+	"container/list"
+	"math"
+	"time"
+)
+
+// The timer wheel has one level per row. A bucket of level i spans 1<<shift
+// nanoseconds, starting at about 1ms, and a level spans as much time as a
+// single bucket of the next level. The last level is a single overflow bucket.
+var (
+	wheelBuckets = [...]int{64, 64, 64, 64, 64, 1}
+	wheelShifts  = [...]uint{20, 26, 32, 38, 44, 50}
+)
+
+// wheelMaxTime is the latest time representable in Unix nanoseconds, about
+// the year 2262. Later times are clamped to it and stay in the overflow
+// bucket.
+var wheelMaxTime = time.Unix(0, math.MaxInt64)
+
+// timerWheel is a hierarchical timing wheel indexing items by the time they
+// are due. Scheduling an item is O(1), and advancing the wheel only
+// visits the buckets whose time has passed, cascading items of higher levels
+// into lower levels as their expiration time approaches.
+type timerWheel[K comparable, V any] struct {
+	buckets [len(wheelBuckets)][]*list.List
+	time    int64
+
+	// pending holds the buckets left to visit by an advance which has been
+	// stopped by its limit, and left the number of items left to visit in
+	// the first of them, or -1 if it has not been started.
+	pending []*list.List
+	left    int
+}
+
+func newTimerWheel[K comparable, V any](now time.Time) *timerWheel[K, V] {
+	w := &timerWheel[K, V]{time: wheelTime(now), left: -1}
+	for i, n := range wheelBuckets {
+		w.buckets[i] = make([]*list.List, n)
+		for j := range w.buckets[i] {
+			w.buckets[i][j] = list.New()
+		}
+	}
+	return w
+}
+
+// schedule (re)inserts item to be due at t.
+func (w *timerWheel[K, V]) schedule(item *cacheItem[K, V], t time.Time) {
+	w.unschedule(item)
+	item.timerTime = wheelTime(t)
+	w.insert(item)
+}
+
+// wheelTime returns t in Unix nanoseconds, clamped to wheelMaxTime.
+func wheelTime(t time.Time) int64 {
+	if t.After(wheelMaxTime) {
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
+
+func (w *timerWheel[K, V]) insert(item *cacheItem[K, V]) {
+	bucket := w.findBucket(max(item.timerTime, w.time))
+	item.timerBucket = bucket
+	item.timer = bucket.PushBack(item)
+}
+
+func (w *timerWheel[K, V]) unschedule(item *cacheItem[K, V]) {
+	if item.timer != nil {
+		item.timerBucket.Remove(item.timer)
+		item.timer = nil
+		item.timerBucket = nil
+	}
+}
+
+func (w *timerWheel[K, V]) findBucket(t int64) *list.List {
+	d := t - w.time
+	last := len(w.buckets) - 1
+	for i := 0; i < last; i++ {
+		if d < 1<<wheelShifts[i+1] {
+			ticks := t >> wheelShifts[i]
+			return w.buckets[i][ticks&int64(len(w.buckets[i])-1)]
+		}
+	}
+	return w.buckets[last][0]
+}
+
+// advance moves the wheel to now and calls expire for every item which is
+// due. A positive limit caps the number of items visited; advance then
+// returns false if it stopped early, and the next call continues where it
+// stopped before moving on to its own now. Once advance returns true, no item
+// which has been scheduled before is due before now.
+func (w *timerWheel[K, V]) advance(now time.Time, limit int, expire func(*cacheItem[K, V])) bool {
+	visited := 0
+	if !w.visit(limit, &visited, expire) {
+		return false
+	}
+	previous := w.time
+	w.time = wheelTime(now)
+	for i := range w.buckets {
+		previousTicks := previous >> wheelShifts[i]
+		delta := max(w.time>>wheelShifts[i]-previousTicks, 0)
+		// the current bucket of the lowest level may hold expired items, even
+		// if the wheel has not moved on to the next bucket
+		if delta == 0 && i > 0 {
+			break
+		}
+		buckets := w.buckets[i]
+		mask := int64(len(buckets) - 1)
+		steps := min(delta+1, int64(len(buckets)))
+		for ticks := previousTicks; ticks < previousTicks+steps; ticks++ {
+			if bucket := buckets[ticks&mask]; bucket.Len() > 0 {
+				w.pending = append(w.pending, bucket)
+			}
+		}
+	}
+	return w.visit(limit, &visited, expire)
+}
+
+// visit visits the pending buckets of an advance, counting the items in
+// visited. Due items are passed to expire, the others are rescheduled. It
+// returns false if a positive limit of visited items is reached first.
+func (w *timerWheel[K, V]) visit(limit int, visited *int, expire func(*cacheItem[K, V])) bool {
+	for len(w.pending) > 0 {
+		bucket := w.pending[0]
+		if w.left < 0 {
+			// rescheduling may put items back at the end of bucket, so only
+			// the items present before are visited
+			w.left = bucket.Len()
+		}
+		for ; w.left > 0 && bucket.Len() > 0; w.left-- {
+			if limit > 0 && *visited >= limit {
+				return false
+			}
+			*visited++
+			item := bucket.Remove(bucket.Front()).(*cacheItem[K, V])
+			item.timer = nil
+			item.timerBucket = nil
+			if item.timerTime < w.time {
+				expire(item)
+			} else {
+				w.insert(item)
+			}
+		}
+		w.pending = w.pending[1:]
+		w.left = -1
+	}
+	return true
+}
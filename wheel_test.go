@@ -0,0 +1,209 @@
+package gcache
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTimerWheelAdvance(t *testing.T) {
+	clock := NewFakeClock()
+	start := clock.Now()
+	w := newTimerWheel[int, int](start)
+
+	rnd := rand.New(rand.NewSource(1))
+	items := make(map[int]*cacheItem[int, int])
+	for i := 0; i < 10000; i++ {
+		// expirations from a microsecond up to about 18 years
+		exp := start.Add(time.Duration(rnd.Int63n(1 << uint(10+rnd.Intn(50)))))
+		item := &cacheItem[int, int]{key: i, expiration: &exp}
+		items[i] = item
+		w.schedule(item, exp)
+	}
+
+	steps := []time.Duration{
+		time.Microsecond, 300 * time.Microsecond, time.Millisecond, 70 * time.Millisecond,
+		time.Second, 5 * time.Second, time.Minute, time.Hour, 30 * time.Hour, 400 * time.Hour,
+		24 * 100 * time.Hour, 24 * 1000 * time.Hour, 24 * 10000 * time.Hour,
+	}
+	for _, step := range steps {
+		clock.Advance(step)
+		now := clock.Now()
+		w.advance(now, 0, func(item *cacheItem[int, int]) {
+			if !item.expiration.Before(now) {
+				t.Fatalf("item %v expired early", item.key)
+			}
+			delete(items, item.key)
+		})
+		for key, item := range items {
+			if item.expiration.Before(now) {
+				t.Fatalf("item %v should have expired after %v", key, now.Sub(start))
+			}
+			if item.timer == nil {
+				t.Fatalf("item %v is not scheduled", key)
+			}
+		}
+	}
+	if len(items) != 0 {
+		t.Fatalf("%v items left", len(items))
+	}
+}
+
+func TestTimerWheelAdvanceLimit(t *testing.T) {
+	clock := NewFakeClock()
+	w := newTimerWheel[int, int](clock.Now())
+	items := make(map[int]*cacheItem[int, int])
+	for i := 0; i < 1000; i++ {
+		exp := clock.Now().Add(time.Duration(i%10+1) * time.Second)
+		item := &cacheItem[int, int]{key: i, expiration: &exp}
+		items[i] = item
+		w.schedule(item, exp)
+	}
+	// not due before the second advance
+	late := &cacheItem[int, int]{key: -1}
+	w.schedule(late, clock.Now().Add(time.Hour))
+
+	clock.Advance(time.Minute)
+	now := clock.Now()
+	calls := 0
+	for {
+		expired := 0
+		done := w.advance(now, 50, func(item *cacheItem[int, int]) {
+			expired++
+			delete(items, item.key)
+		})
+		if expired > 50 {
+			t.Fatalf("%v items expired beyond the limit", expired)
+		}
+		calls++
+		if done {
+			break
+		}
+		// items scheduled between the calls are kept
+		item := &cacheItem[int, int]{key: 1000 + calls}
+		w.schedule(item, now.Add(time.Minute))
+	}
+	if len(items) != 0 || calls < 20 {
+		t.Fatalf("%v items left after %v calls", len(items), calls)
+	}
+
+	clock.Advance(2 * time.Hour)
+	expired := 0
+	for !w.advance(clock.Now(), 10, func(*cacheItem[int, int]) { expired++ }) {
+	}
+	if expired != calls {
+		t.Fatalf("%v != %v", expired, calls)
+	}
+}
+
+func TestTimerWheelReschedule(t *testing.T) {
+	clock := NewFakeClock()
+	w := newTimerWheel[int, int](clock.Now())
+
+	exp := clock.Now().Add(time.Second)
+	item := &cacheItem[int, int]{key: 1}
+	w.schedule(item, exp)
+	w.schedule(item, clock.Now().Add(time.Hour))
+
+	clock.Advance(time.Minute)
+	w.advance(clock.Now(), 0, func(*cacheItem[int, int]) {
+		t.Fatal("rescheduled item should not expire")
+	})
+
+	w.unschedule(item)
+	clock.Advance(2 * time.Hour)
+	w.advance(clock.Now(), 0, func(*cacheItem[int, int]) {
+		t.Fatal("unscheduled item should not expire")
+	})
+}
+
+func TestTimerWheelFarFuture(t *testing.T) {
+	clock := NewFakeClock()
+	clock.Advance(50 * 365 * 24 * time.Hour)
+	w := newTimerWheel[int, int](clock.Now())
+
+	// beyond the range of Unix nanoseconds
+	item := &cacheItem[int, int]{key: 1}
+	w.schedule(item, clock.Now().Add(250*365*24*time.Hour))
+	if item.timerTime != math.MaxInt64 {
+		t.Fatalf("%v != %v", item.timerTime, int64(math.MaxInt64))
+	}
+	clock.Advance(time.Hour)
+	w.advance(clock.Now(), 0, func(*cacheItem[int, int]) {
+		t.Fatal("far future item should not expire")
+	})
+
+	gc := New[int, int](10).LRU().Clock(clock).Build()
+	gc.SetWithExpire(1, 1, 250*365*24*time.Hour)
+	if !gc.Has(1) {
+		t.Fatal("should have 1")
+	}
+	if l := gc.Len(true); l != 1 {
+		t.Fatalf("%v != 1", l)
+	}
+	if !gc.Has(1) {
+		t.Fatal("should still have 1")
+	}
+}
+
+func TestExpiredItemsRemoved(t *testing.T) {
+	for _, tp := range []string{TYPE_SIMPLE, TYPE_LRU, TYPE_LFU, TYPE_ARC, TYPE_TINYLFU, TYPE_S3FIFO, TYPE_SIEVE} {
+		t.Run(tp, func(t *testing.T) {
+			clock := NewFakeClock()
+			evicted := 0
+			gc := New[int, int](100).
+				EvictType(tp).
+				Clock(clock).
+				EvictedFunc(func(int, int) { evicted++ }).
+				Build()
+			for i := 0; i < 50; i++ {
+				gc.SetWithExpire(i, i, time.Duration(i+1)*time.Minute)
+			}
+			for i := 50; i < 60; i++ {
+				gc.Set(i, i)
+			}
+
+			clock.Advance(20*time.Minute + time.Second)
+			if l := gc.Len(true); l != 40 {
+				t.Fatalf("%v != 40", l)
+			}
+			if evicted != 20 {
+				t.Fatalf("%v != 20", evicted)
+			}
+			if l := len(gc.Keys(true)); l != 40 {
+				t.Fatalf("%v != 40", l)
+			}
+			clock.Advance(time.Hour)
+			if m := gc.GetALL(true); len(m) != 10 {
+				t.Fatalf("%v != 10", len(m))
+			}
+			if l := gc.Len(false); l != 10 {
+				t.Fatalf("%v != 10", l)
+			}
+		})
+	}
+}
+
+func TestExpiredItemsEvictedFirst(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New[int, int](10).
+		LRU().
+		Clock(clock).
+		Build()
+	for i := 0; i < 5; i++ {
+		gc.Set(i, i)
+	}
+	for i := 5; i < 10; i++ {
+		gc.SetWithExpire(i, i, time.Second)
+	}
+	clock.Advance(time.Minute)
+	for i := 10; i < 15; i++ {
+		gc.Set(i, i)
+	}
+	for i := 0; i < 5; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("%v should not have been evicted", i)
+		}
+	}
+}